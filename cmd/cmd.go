@@ -2,37 +2,53 @@ package main
 
 import (
 	"fmt"
-	"mytodo/lib/agent"
 	"mytodo/lib/commands"
+	"mytodo/lib/config"
+	"mytodo/lib/hooks"
 	"mytodo/lib/tasklist"
+	"mytodo/lib/utils"
+	"mytodo/lib/vault"
 	"os"
 	"path"
 )
 
 var (
 	MasterTasks *tasklist.TaskList
-)
-
-type AgentBackend string
 
-const (
-	OpenAIAgent   AgentBackend = "OpenAI"
-	OllamaAIAgent AgentBackend = "Ollama"
+	// Shell completion must not prompt; it just offers fewer suggestions.
+	completing = commands.IsCompletionRequest(os.Args)
 )
 
-const (
-	TrackFile            = ".mytodo.json"
-	SelectedAgentBackend = OpenAIAgent
-)
+const TrackFile = ".mytodo.json"
 
 func init() {
-	homePath := os.Getenv("HOME")
-	if homePath == "" {
-		homePath = "."
+	// Commands report a broken config file, except "mytodo config" which is
+	// how it gets fixed.
+	commands.SetConfigError(config.Load())
+
+	taskFile := utils.GetTaskFile()
+	if taskFile == "" {
+		homePath := os.Getenv("HOME")
+		if homePath == "" {
+			homePath = "."
+		}
+		taskFile = path.Join(homePath, TrackFile)
 	}
 
-	t := tasklist.NewTaskList(path.Join(homePath, TrackFile))
+	t := tasklist.NewTaskList(taskFile)
 	commands.SetMasterTasks(t)
+	if completing {
+		vault.Interactive = false
+	} else if dir, err := hooks.DefaultDir(); err == nil {
+		t.SetHook(hooks.New(dir, taskFile))
+	}
+	if err := commands.UnlockTaskList(t); err != nil {
+		if completing {
+			return
+		}
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	err := commands.GetTaskList().Load()
 	if err != nil {
 		panic(fmt.Sprintf("Error loading tasks: %v", err))
@@ -40,23 +56,14 @@ func init() {
 }
 
 func main() {
-	var llmAgent agent.LlmAgent
-	var err error
-
-	if SelectedAgentBackend == OllamaAIAgent {
-		llmAgent = agent.CreateLlmAgentDefault()
-
-	} else if SelectedAgentBackend == OpenAIAgent {
-		llmAgent, err = agent.CreateOpenAIAgentDefault()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+	rootCmd := commands.PrepareCommands()
+	if ran, code := commands.RunPlugin(rootCmd, os.Args[1:]); ran {
+		os.Exit(code)
 	}
 
-	commands.SetAgent(llmAgent)
-	err = commands.PrepareCommands().Execute()
-	if err != nil {
-		fmt.Println("Error:", err)
+	commands.SetAgentFactory(commands.NewConfiguredAgent)
+	// cobra has already printed the error to stderr.
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
 	}
 }
@@ -2,12 +2,15 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mytodo/lib/utils"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 )
 
 // -----------------------------------------------------------------------------
@@ -61,9 +64,23 @@ func (l *LlmResponse) GetResponse() (string, error) {
 // -----------------------------------------------------------------------------
 // LlmAgent interface
 // -----------------------------------------------------------------------------
+//
+// Every call ends when ctx is done and, with a timeout configured, when that
+// passes; 429 and 5xx replies are retried with backoff first. Errors wrap
+// ErrRateLimited, ErrAuth, ErrTimeout, ErrUnavailable or ErrBadResponse when
+// one applies, and context.Canceled when ctx was canceled.
 type LlmAgent interface {
-	Prompt(prompt string) (*LlmResponse, error)
-	AskConfirmation(question string) (bool, error)
+	Prompt(ctx context.Context, prompt string) (*LlmResponse, error)
+	// Stream sends a prompt and calls onToken with each piece of the reply as
+	// it arrives. It returns the whole reply; an error from onToken stops it.
+	Stream(ctx context.Context, prompt string, onToken func(token string) error) (string, error)
+	// PromptJSON asks for a reply that follows the JSON schema, using the
+	// backend's structured output mode, and returns the JSON text. See
+	// PromptInto for decoding and checking it.
+	PromptJSON(ctx context.Context, prompt string, schema Schema) (string, error)
+	AskConfirmation(ctx context.Context, question string) (bool, error)
+	// Models lists the models the backend offers.
+	Models(ctx context.Context) ([]string, error)
 }
 
 // -----------------------------------------------------------------------------
@@ -77,54 +94,132 @@ type NetClient interface {
 // OllamaAgent implementation
 // -----------------------------------------------------------------------------
 type OllamaAgent struct {
-	client  NetClient
-	baseURL string
+	caller
+	baseURL     string
+	model       string
+	temperature *float64 // nil leaves the model's default
+	maxTokens   int      // 0 leaves the model's default
 }
 
-func (oa *OllamaAgent) Prompt(prompt string) (*LlmResponse, error) {
-	// Build request payload
-	payload := map[string]interface{}{
-		"model":  "gpt-oss:20b",
-		"prompt": prompt,
-		"stream": false,
+func (oa *OllamaAgent) Prompt(ctx context.Context, prompt string) (*LlmResponse, error) {
+	ctx, cancel := oa.deadline(ctx)
+	defer cancel()
+
+	resp, err := oa.generate(ctx, prompt, false, nil)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	body, err := json.Marshal(payload)
+	// Read response body
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		return nil, requestError(ctx, fmt.Errorf("failed to read response: %w", err))
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", oa.baseURL+"/api/generate", bytes.NewReader(body))
+	// Wrap into LlmResponse
+	return &LlmResponse{raw: respBody}, nil
+}
+
+// Stream asks Ollama for a streamed reply: one JSON object per line, each
+// carrying the next piece of the reply, until one has "done" set.
+func (oa *OllamaAgent) Stream(ctx context.Context, prompt string, onToken func(token string) error) (string, error) {
+	ctx, cancel := oa.deadline(ctx)
+	defer cancel()
+
+	resp, err := oa.generate(ctx, prompt, true, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	defer resp.Body.Close()
+
+	var reply strings.Builder
+	err = readLines(ctx, resp.Body, func(line string) (bool, error) {
+		if strings.TrimSpace(line) == "" {
+			return false, nil
+		}
+		var chunk struct {
+			Response string `json:"response"`
+			Done     bool   `json:"done"`
+			Error    string `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return false, fmt.Errorf("%w: failed to parse stream: %w", ErrBadResponse, err)
+		}
+		if chunk.Error != "" {
+			return false, fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		if chunk.Response != "" {
+			reply.WriteString(chunk.Response)
+			if err := onToken(chunk.Response); err != nil {
+				return false, err
+			}
+		}
+		return chunk.Done, nil
+	})
+	return reply.String(), err
+}
+
+// PromptJSON passes the schema as Ollama's format, which constrains the reply
+// to it.
+func (oa *OllamaAgent) PromptJSON(ctx context.Context, prompt string, schema Schema) (string, error) {
+	ctx, cancel := oa.deadline(ctx)
+	defer cancel()
 
-	// Execute request
-	resp, err := oa.client.Do(req)
+	resp, err := oa.generate(ctx, prompt, false, schema.Schema)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return "", err
 	}
 	defer resp.Body.Close()
 
-	// Read response body
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return "", requestError(ctx, fmt.Errorf("failed to read response: %w", err))
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non‑OK HTTP status: %s, body: %s", resp.Status, string(respBody))
+	var data InternalResp
+	if err := json.Unmarshal(respBody, &data); err != nil {
+		return "", fmt.Errorf("%w: failed to parse response: %w", ErrBadResponse, err)
 	}
+	return data.Response, nil
+}
 
-	// Wrap into LlmResponse
-	return &LlmResponse{raw: respBody}, nil
+// generate posts a prompt to /api/generate and returns the OK response. A
+// non-nil format asks for JSON following that schema.
+func (oa *OllamaAgent) generate(ctx context.Context, prompt string, stream bool, format map[string]interface{}) (*http.Response, error) {
+	// Build request payload
+	payload := map[string]interface{}{
+		"model":  oa.model,
+		"prompt": prompt,
+		"stream": stream,
+	}
+	if format != nil {
+		payload["format"] = format
+	}
+	options := map[string]interface{}{}
+	if oa.temperature != nil {
+		options["temperature"] = *oa.temperature
+	}
+	if oa.maxTokens > 0 {
+		options["num_predict"] = oa.maxTokens
+	}
+	if len(options) > 0 {
+		payload["options"] = options
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	return oa.send(ctx, "POST", oa.baseURL+"/api/generate", body, http.Header{
+		"Content-Type": {"application/json"},
+	})
 }
 
-func (oa *OllamaAgent) AskConfirmation(question string) (bool, error) {
+func (oa *OllamaAgent) AskConfirmation(ctx context.Context, question string) (bool, error) {
 	// Re‑use the Prompt logic – it builds the request, sends it, and
 	// returns an LlmResponse containing the raw JSON payload.
-	lr, err := oa.Prompt(question)
+	lr, err := oa.Prompt(ctx, question)
 	if err != nil {
 		return false, fmt.Errorf("AskConfirmation prompt failed: %w", err)
 	}
@@ -140,14 +235,36 @@ func (oa *OllamaAgent) AskConfirmation(question string) (bool, error) {
 	return txt == "yes" || txt == "y", nil
 }
 
+// Models lists the models pulled into the Ollama server.
+func (oa *OllamaAgent) Models(ctx context.Context) ([]string, error) {
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := oa.getJSON(ctx, oa.baseURL+"/api/tags", nil, &tags); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, model := range tags.Models {
+		names = append(names, model.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // ---------------------------------------------------------------------------
 // OpenAIAgent implementation
 // ---------------------------------------------------------------------------
 
 type OpenAIAgent struct {
-	client  NetClient
-	baseURL string
-	apiKey  string
+	caller
+	baseURL     string
+	apiKey      string
+	model       string
+	temperature float64
+	maxTokens   int
 }
 
 // From official OpenAI document
@@ -160,51 +277,147 @@ type OpenAIResponse struct {
 	} `json:"output"`
 }
 
-func (oa *OpenAIAgent) Prompt(prompt string) (*LlmResponse, error) {
-	// Build request payload
-	payload := map[string]interface{}{
-		"model":             "gpt-4.1",
-		"input":             prompt,
-		"max_output_tokens": 4096,
-		"temperature":       0.7,
+func (oa *OpenAIAgent) Prompt(ctx context.Context, prompt string) (*LlmResponse, error) {
+	ctx, cancel := oa.deadline(ctx)
+	defer cancel()
+
+	resp, err := oa.respond(ctx, prompt, false, nil)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	body, err := json.Marshal(payload)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		return nil, requestError(ctx, fmt.Errorf("failed to read response: %w", err))
 	}
 
-	req, err := http.NewRequest("POST", oa.baseURL+"/v1/responses", bytes.NewReader(body))
+	return extractOpenAiResponse(respBody)
+}
+
+// Stream asks OpenAI for a streamed reply: server-sent events, of which
+// response.output_text.delta carry the next piece of the reply, until
+// response.completed.
+func (oa *OpenAIAgent) Stream(ctx context.Context, prompt string, onToken func(token string) error) (string, error) {
+	ctx, cancel := oa.deadline(ctx)
+	defer cancel()
+
+	resp, err := oa.respond(ctx, prompt, true, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+oa.apiKey)
+	defer resp.Body.Close()
+
+	var reply strings.Builder
+	err = readLines(ctx, resp.Body, func(line string) (bool, error) {
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			// Event names, comments and the blank lines between events.
+			return false, nil
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return true, nil
+		}
+		var event struct {
+			Type     string `json:"type"`
+			Delta    string `json:"delta"`
+			Message  string `json:"message"`
+			Response struct {
+				Error *struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			} `json:"response"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return false, fmt.Errorf("%w: failed to parse stream: %w", ErrBadResponse, err)
+		}
+		switch event.Type {
+		case "response.output_text.delta":
+			reply.WriteString(event.Delta)
+			if err := onToken(event.Delta); err != nil {
+				return false, err
+			}
+		case "response.completed", "response.incomplete":
+			return true, nil
+		case "response.failed":
+			if event.Response.Error != nil {
+				return false, fmt.Errorf("openai: %s", event.Response.Error.Message)
+			}
+			return false, fmt.Errorf("openai: response failed")
+		case "error":
+			return false, fmt.Errorf("openai: %s", event.Message)
+		}
+		return false, nil
+	})
+	return reply.String(), err
+}
 
-	resp, err := oa.client.Do(req)
+// PromptJSON asks for a reply in the json_schema text format. The schema is
+// not sent as strict, since strict mode wants every property required.
+func (oa *OpenAIAgent) PromptJSON(ctx context.Context, prompt string, schema Schema) (string, error) {
+	ctx, cancel := oa.deadline(ctx)
+	defer cancel()
+
+	resp, err := oa.respond(ctx, prompt, false, &schema)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return "", requestError(ctx, fmt.Errorf("failed to read response: %w", err))
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non‑OK HTTP status: %s, body: %s", resp.Status, string(respBody))
+	lr, err := extractOpenAiResponse(respBody)
+	if err != nil {
+		return "", err
 	}
+	return lr.GetResponse()
+}
 
-	return extractOpenAiResponse(respBody)
+// respond posts a prompt to /v1/responses and returns the OK response. A
+// non-nil schema asks for JSON following it.
+func (oa *OpenAIAgent) respond(ctx context.Context, prompt string, stream bool, schema *Schema) (*http.Response, error) {
+	// Build request payload
+	payload := map[string]interface{}{
+		"model":             oa.model,
+		"input":             prompt,
+		"max_output_tokens": oa.maxTokens,
+		"temperature":       oa.temperature,
+	}
+	if stream {
+		payload["stream"] = true
+	}
+	if schema != nil {
+		payload["text"] = map[string]interface{}{
+			"format": map[string]interface{}{
+				"type":   "json_schema",
+				"name":   schema.Name,
+				"schema": schema.Schema,
+				"strict": false,
+			},
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	return oa.send(ctx, "POST", oa.baseURL+"/v1/responses", body, http.Header{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"Bearer " + oa.apiKey},
+	})
 }
 
 // AskConfirmation sends a short yes/no prompt to the LLM and interprets
 // the textual response as a boolean.  It uses the same HTTP client as
 // Prompt() – no external SDK required.
-func (oa *OpenAIAgent) AskConfirmation(question string) (bool, error) {
+func (oa *OpenAIAgent) AskConfirmation(ctx context.Context, question string) (bool, error) {
 	// Re‑use the Prompt logic – it builds the request, sends it, and
 	// returns an LlmResponse containing the raw JSON payload.
-	lr, err := oa.Prompt(question)
+	lr, err := oa.Prompt(ctx, question)
 	if err != nil {
 		return false, fmt.Errorf("AskConfirmation prompt failed: %w", err)
 	}
@@ -219,6 +432,27 @@ func (oa *OpenAIAgent) AskConfirmation(question string) (bool, error) {
 	txt = strings.ToLower(strings.TrimSpace(txt))
 	return txt == "yes" || txt == "y", nil
 }
+
+// Models lists the models the API key can use.
+func (oa *OpenAIAgent) Models(ctx context.Context) ([]string, error) {
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	header := http.Header{"Authorization": {"Bearer " + oa.apiKey}}
+	if err := oa.getJSON(ctx, oa.baseURL+"/v1/models", header, &list); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, model := range list.Data {
+		names = append(names, model.ID)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 func extractOpenAiResponse(openAiResponse []byte) (*LlmResponse, error) {
 	if openAiResponse == nil {
 		return nil, fmt.Errorf("Input is empty")
@@ -226,11 +460,11 @@ func extractOpenAiResponse(openAiResponse []byte) (*LlmResponse, error) {
 
 	var openAiResp OpenAIResponse
 	if err := json.Unmarshal(openAiResponse, &openAiResp); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrBadResponse, err)
 	}
 
 	if len(openAiResp.Output) == 0 || len(openAiResp.Output[0].Content) == 0 {
-		return nil, fmt.Errorf("no output text in response: %s", string(openAiResponse))
+		return nil, fmt.Errorf("%w: no output text in response: %s", ErrBadResponse, string(openAiResponse))
 	}
 
 	internalResp := InternalResp{Response: openAiResp.Output[0].Content[0].Text}
@@ -243,10 +477,85 @@ func extractOpenAiResponse(openAiResponse []byte) (*LlmResponse, error) {
 // Factory functions
 // ---------------------------------------------------------------------------
 
+// Backends and the defaults used when a setting is left empty.
+const (
+	BackendOpenAI = "openai"
+	BackendOllama = "ollama"
+
+	DefaultOllamaURL   = "http://localhost:11434"
+	DefaultOllamaModel = "gpt-oss:20b"
+	DefaultOpenAIURL   = "https://api.openai.com"
+	DefaultOpenAIModel = "gpt-4.1"
+
+	DefaultOpenAITemperature = 0.7
+	DefaultOpenAIMaxTokens   = 4096
+)
+
+// Settings select a backend and how it is asked. Zero values select the
+// backend's defaults.
+type Settings struct {
+	Backend     string
+	Model       string
+	Endpoint    string
+	APIKey      string
+	Temperature *float64
+	MaxTokens   int
+	Timeout     time.Duration // per call, retries included; 0 waits as long as it takes
+	Retries     *int          // of 429 and 5xx replies; nil for DefaultRetries
+}
+
+// New creates the agent for the settings' backend.
+func New(s Settings) (LlmAgent, error) {
+	// The deadline of each call bounds it, streamed replies included, rather
+	// than a client timeout.
+	c := caller{client: &http.Client{}, timeout: s.Timeout, retries: DefaultRetries}
+	if s.Retries != nil {
+		c.retries = *s.Retries
+	}
+	switch s.Backend {
+	case BackendOllama:
+		return &OllamaAgent{
+			caller:      c,
+			baseURL:     strings.TrimSuffix(orDefault(s.Endpoint, DefaultOllamaURL), "/"),
+			model:       orDefault(s.Model, DefaultOllamaModel),
+			temperature: s.Temperature,
+			maxTokens:   s.MaxTokens,
+		}, nil
+	case BackendOpenAI, "":
+		if s.APIKey == "" {
+			return nil, fmt.Errorf("no OpenAI API key: set agent.api_key or OPEN_AI_API_KEY")
+		}
+		agent := &OpenAIAgent{
+			caller:      c,
+			baseURL:     strings.TrimSuffix(orDefault(s.Endpoint, DefaultOpenAIURL), "/"),
+			apiKey:      s.APIKey,
+			model:       orDefault(s.Model, DefaultOpenAIModel),
+			temperature: DefaultOpenAITemperature,
+			maxTokens:   DefaultOpenAIMaxTokens,
+		}
+		if s.Temperature != nil {
+			agent.temperature = *s.Temperature
+		}
+		if s.MaxTokens > 0 {
+			agent.maxTokens = s.MaxTokens
+		}
+		return agent, nil
+	}
+	return nil, fmt.Errorf("unknown agent backend %q: use openai or ollama", s.Backend)
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
 func CreateLlmAgent(client NetClient) LlmAgent {
 	return &OllamaAgent{
-		client:  client,
-		baseURL: "http://localhost:11434",
+		caller:  caller{client: client, retries: DefaultRetries},
+		baseURL: DefaultOllamaURL,
+		model:   DefaultOllamaModel,
 	}
 }
 
@@ -261,16 +570,22 @@ func CreateLlmAgentDefault() LlmAgent {
 
 func CreateOpenAIAgent(client NetClient, apiKey string) LlmAgent {
 	return &OpenAIAgent{
-		client:  client,
-		baseURL: "https://api.openai.com",
-		apiKey:  apiKey,
+		caller:      caller{client: client, retries: DefaultRetries},
+		baseURL:     DefaultOpenAIURL,
+		apiKey:      apiKey,
+		model:       DefaultOpenAIModel,
+		temperature: DefaultOpenAITemperature,
+		maxTokens:   DefaultOpenAIMaxTokens,
 	}
 }
 
 func CreateOpenAIAgentDefault() (LlmAgent, error) {
-	apiKey := utils.GetOpenAIToken()
+	apiKey, err := utils.GetOpenAIToken()
+	if err != nil {
+		return nil, err
+	}
 	if apiKey == "" {
-		return nil, fmt.Errorf("environment variable OPEN_AI_API_KEY not set")
+		return nil, fmt.Errorf("no OpenAI API key: set agent.api_key or OPEN_AI_API_KEY")
 	}
 	httpClient := &http.Client{}
 	return CreateOpenAIAgent(httpClient, apiKey), nil
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// DefaultAttempts is how often PromptInto asks before giving up on a reply
+// that does not parse or validate.
+const DefaultAttempts = 3
+
+// Schema describes the JSON a prompt must produce. Name identifies it to
+// backends that want one; Schema is a JSON Schema whose root is an object,
+// as structured output modes require.
+type Schema struct {
+	Name   string
+	Schema map[string]interface{}
+}
+
+// PromptInto asks for JSON following the schema and decodes it into v, a
+// pointer, rejecting keys v has no field for. validate, when not nil, checks
+// the decoded value; v is only set once a reply passes. A reply that does not
+// decode or validate is sent back to the model with the error, up to attempts
+// times in all; failed requests are not retried.
+func PromptInto(ctx context.Context, a LlmAgent, prompt string, schema Schema, v interface{}, validate func(v interface{}) error, attempts int) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	ask := prompt
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		reply, err := a.PromptJSON(ctx, ask, schema)
+		if err != nil {
+			return err
+		}
+
+		// Each reply decodes into a fresh value, so that nothing of a rejected
+		// one carries over.
+		fresh := reflect.New(reflect.TypeOf(v).Elem())
+		if lastErr = decodeStrict(reply, fresh.Interface()); lastErr == nil && validate != nil {
+			lastErr = validate(fresh.Interface())
+		}
+		if lastErr == nil {
+			reflect.ValueOf(v).Elem().Set(fresh.Elem())
+			return nil
+		}
+
+		ask = fmt.Sprintf(`%s
+
+Your previous answer was:
+%s
+
+It was rejected: %s
+Answer again with JSON that fixes this.`, prompt, reply, lastErr)
+	}
+	return fmt.Errorf("%w: no valid reply after %d attempt(s): %w", ErrBadResponse, attempts, lastErr)
+}
+
+// decodeStrict decodes a single JSON value into v, rejecting unknown keys and
+// trailing text.
+func decodeStrict(reply string, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader([]byte(reply)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if dec.More() {
+		return fmt.Errorf("invalid JSON: unexpected text after the value")
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeAgent answers PromptJSON with the given replies in turn and records
+// the prompts.
+type fakeAgent struct {
+	LlmAgent // other methods are not used
+	replies  []string
+	err      error
+	prompts  []string
+}
+
+func (a *fakeAgent) PromptJSON(ctx context.Context, prompt string, schema Schema) (string, error) {
+	a.prompts = append(a.prompts, prompt)
+	if a.err != nil {
+		return "", a.err
+	}
+	if len(a.prompts) > len(a.replies) {
+		return "", errors.New("unexpected prompt")
+	}
+	return a.replies[len(a.prompts)-1], nil
+}
+
+type item struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+type items struct {
+	Items []item `json:"items"`
+}
+
+func TestPromptInto(t *testing.T) {
+	// validate rejects items without a name and a count over 10.
+	validate := func(v interface{}) error {
+		for _, it := range v.(*items).Items {
+			if it.Name == "" {
+				return errors.New("name is required")
+			}
+			if it.Count > 10 {
+				return errors.New("count is over 10")
+			}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name        string
+		replies     []string
+		agentErr    error
+		attempts    int
+		want        items
+		wantErr     bool
+		wantIs      error
+		wantPrompts int
+	}{
+		{
+			name:        "valid at once",
+			replies:     []string{`{"items":[{"name":"a","count":1}]}`},
+			attempts:    3,
+			want:        items{Items: []item{{Name: "a", Count: 1}}},
+			wantPrompts: 1,
+		},
+		{
+			name:        "invalid JSON, then valid",
+			replies:     []string{`{"items":[{"name":"a"`, `{"items":[{"name":"a","count":2}]}`},
+			attempts:    3,
+			want:        items{Items: []item{{Name: "a", Count: 2}}},
+			wantPrompts: 2,
+		},
+		{
+			name:        "unknown key, then valid",
+			replies:     []string{`{"items":[{"name":"a","colour":"red"}]}`, `{"items":[{"name":"a"}]}`},
+			attempts:    3,
+			want:        items{Items: []item{{Name: "a"}}},
+			wantPrompts: 2,
+		},
+		{
+			name:        "text after the JSON",
+			replies:     []string{`{"items":[]} Hope this helps!`, `{"items":[]}`},
+			attempts:    3,
+			want:        items{Items: []item{}},
+			wantPrompts: 2,
+		},
+		{
+			name: "rejected reply leaves nothing behind",
+			replies: []string{
+				`{"items":[{"name":"a","count":50,"tags":["stale"]},{"name":"b","count":1}]}`,
+				`{"items":[{"name":"a"}]}`,
+			},
+			attempts:    3,
+			want:        items{Items: []item{{Name: "a"}}},
+			wantPrompts: 2,
+		},
+		{
+			name:        "attempts used up",
+			replies:     []string{`{"items":[{"count":1}]}`, `{"items":[{"count":2}]}`},
+			attempts:    2,
+			wantErr:     true,
+			wantIs:      ErrBadResponse,
+			wantPrompts: 2,
+		},
+		{
+			name:        "failed requests are not retried",
+			agentErr:    ErrRateLimited,
+			attempts:    3,
+			wantErr:     true,
+			wantIs:      ErrRateLimited,
+			wantPrompts: 1,
+		},
+		{
+			name:        "at least one attempt",
+			replies:     []string{`{"items":[]}`},
+			attempts:    0,
+			want:        items{Items: []item{}},
+			wantPrompts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &fakeAgent{replies: tt.replies, err: tt.agentErr}
+			var got items
+			err := PromptInto(context.Background(), a, "List items.", Schema{Name: "items"}, &got, validate, tt.attempts)
+
+			if len(a.prompts) != tt.wantPrompts {
+				t.Errorf("prompts = %d, want %d", len(a.prompts), tt.wantPrompts)
+			}
+			if tt.wantErr {
+				if err == nil || (tt.wantIs != nil && !errors.Is(err, tt.wantIs)) {
+					t.Fatalf("PromptInto() error = %v, want %v", err, tt.wantIs)
+				}
+				if !reflect.DeepEqual(got, items{}) {
+					t.Errorf("PromptInto() set %+v after failing", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PromptInto() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PromptInto() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptIntoSendsBackTheRejection(t *testing.T) {
+	a := &fakeAgent{replies: []string{`{"items":[{"name":""}]}`, `{"items":[{"name":"a"}]}`}}
+	var got items
+	err := PromptInto(context.Background(), a, "List items.", Schema{Name: "items"}, &got, func(v interface{}) error {
+		if v.(*items).Items[0].Name == "" {
+			return errors.New("name is required")
+		}
+		return nil
+	}, 2)
+	if err != nil {
+		t.Fatalf("PromptInto() error = %v", err)
+	}
+
+	retry := a.prompts[1]
+	for _, part := range []string{"List items.", `{"items":[{"name":""}]}`, "name is required"} {
+		if !strings.Contains(retry, part) {
+			t.Errorf("retry prompt lacks %q:\n%s", part, retry)
+		}
+	}
+}
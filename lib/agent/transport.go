@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Kinds of failure commands may want to explain. Errors returned by agents
+// wrap one of these when it applies; test with errors.Is.
+var (
+	ErrRateLimited = errors.New("rate limited by the AI backend")
+	ErrAuth        = errors.New("the AI backend rejected the credentials")
+	ErrTimeout     = errors.New("the AI backend did not answer in time")
+	ErrUnavailable = errors.New("the AI backend is unavailable")
+	ErrBadResponse = errors.New("the AI backend sent an unusable reply")
+)
+
+// StatusError is a reply with a status other than 200 OK. It unwraps to
+// ErrRateLimited, ErrAuth, ErrTimeout or ErrUnavailable by status.
+type StatusError struct {
+	Code   int
+	Status string
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("non‑OK HTTP status: %s, body: %s", e.Status, e.Body)
+}
+
+func (e *StatusError) Unwrap() error {
+	switch {
+	case e.Code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.Code == http.StatusUnauthorized || e.Code == http.StatusForbidden:
+		return ErrAuth
+	case e.Code == http.StatusRequestTimeout || e.Code == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case e.Code >= 500:
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// retryable reports whether the request may succeed when sent again.
+func (e *StatusError) retryable() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code >= 500
+}
+
+// Retry defaults: how often 429 and 5xx replies are retried, and the bounds
+// of the exponential backoff between attempts.
+const (
+	DefaultRetries = 3
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// caller sends an agent's requests. Each call gets timeout as its deadline,
+// retries included, and 429 and 5xx replies are retried up to retries times.
+type caller struct {
+	client  NetClient
+	timeout time.Duration // 0 waits as long as the context allows
+	retries int
+}
+
+// deadline bounds one call by the timeout.
+func (c caller) deadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// send sends a request built from method, url, body and header, and returns
+// the response when its status is OK. Otherwise the body is read into a
+// StatusError and the response closed. 429 and 5xx replies are sent again
+// after a backoff, or after the server's Retry-After when it gives one.
+func (c caller) send(ctx context.Context, method, url string, body []byte, header http.Header) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, requestError(ctx, err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		statusErr := &StatusError{Code: resp.StatusCode, Status: resp.Status, Body: string(respBody)}
+		if !statusErr.retryable() || attempt >= c.retries {
+			if attempt > 0 {
+				return nil, fmt.Errorf("after %d attempts: %w", attempt+1, statusErr)
+			}
+			return nil, statusErr
+		}
+
+		wait := backoff(attempt)
+		if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			wait = after
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			// Waiting would only end in a timeout; report the real reason.
+			return nil, fmt.Errorf("not retrying, as the wait of %s passes the deadline: %w", wait.Round(time.Second), statusErr)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, requestError(ctx, fmt.Errorf("waiting to retry %s: %w", statusErr.Status, ctx.Err()))
+		}
+	}
+}
+
+// backoff returns the wait before retry attempt+1: an exponentially growing
+// delay, capped, of which a random half is taken off so that clients do not
+// retry in step.
+func backoff(attempt int) time.Duration {
+	delay := retryMaxDelay
+	if attempt < 16 {
+		delay = min(retryBaseDelay<<attempt, retryMaxDelay)
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryAfter parses a Retry-After header: delay seconds or an HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// requestError wraps a failed request or read in ErrTimeout when the
+// deadline passed or the connection timed out. Cancellation, e.g. by Ctrl-C,
+// is left as context.Canceled.
+func requestError(ctx context.Context, err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	if errors.Is(ctx.Err(), context.Canceled) && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%w: %w", context.Canceled, err)
+	}
+	return fmt.Errorf("request failed: %w", err)
+}
+
+// getJSON sends a GET request and decodes the JSON reply into v.
+func (c caller) getJSON(ctx context.Context, url string, header http.Header, v interface{}) error {
+	ctx, cancel := c.deadline(ctx)
+	defer cancel()
+
+	resp, err := c.send(ctx, "GET", url, nil, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return requestError(ctx, err)
+	}
+	if err := json.Unmarshal(respBody, v); err != nil {
+		return fmt.Errorf("%w: failed to parse response: %w", ErrBadResponse, err)
+	}
+	return nil
+}
+
+// readLines calls handle with each line of a streamed body until it reports
+// the stream is done. A body that ends before that is an error.
+func readLines(ctx context.Context, body io.Reader, handle func(line string) (done bool, err error)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		done, err := handle(scanner.Text())
+		if err != nil || done {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return requestError(ctx, fmt.Errorf("failed to read stream: %w", err))
+	}
+	return fmt.Errorf("%w: stream ended before the reply was complete", ErrBadResponse)
+}
@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClient answers requests with the given replies in turn and records
+// how many requests it got.
+type fakeClient struct {
+	replies  []fakeReply
+	requests int
+}
+
+type fakeReply struct {
+	status     int
+	retryAfter string
+	err        error
+}
+
+func (c *fakeClient) Do(req *http.Request) (*http.Response, error) {
+	if c.requests >= len(c.replies) {
+		return nil, errors.New("unexpected request")
+	}
+	reply := c.replies[c.requests]
+	c.requests++
+	if reply.err != nil {
+		return nil, reply.err
+	}
+	header := http.Header{}
+	if reply.retryAfter != "" {
+		header.Set("Retry-After", reply.retryAfter)
+	}
+	return &http.Response{
+		StatusCode: reply.status,
+		Status:     http.StatusText(reply.status),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(`{"ok":true}`)),
+	}, nil
+}
+
+func TestCallerSend(t *testing.T) {
+	ok := fakeReply{status: http.StatusOK}
+	now := func(status int) fakeReply { return fakeReply{status: status, retryAfter: "0"} }
+
+	tests := []struct {
+		name         string
+		replies      []fakeReply
+		retries      int
+		timeout      time.Duration
+		wantErr      bool
+		wantIs       error
+		wantCode     int // of the StatusError, if any
+		wantRequests int
+	}{
+		{name: "OK at once", replies: []fakeReply{ok}, retries: 3, wantRequests: 1},
+		{name: "429 then OK", replies: []fakeReply{now(429), ok}, retries: 3, wantRequests: 2},
+		{name: "5xx then OK", replies: []fakeReply{now(500), now(502), now(503), ok}, retries: 3, wantRequests: 4},
+		{name: "retries exhausted", replies: []fakeReply{now(503), now(503), now(503)}, retries: 2, wantErr: true, wantIs: ErrUnavailable, wantCode: 503, wantRequests: 3},
+		{name: "no retries", replies: []fakeReply{now(429)}, retries: 0, wantErr: true, wantIs: ErrRateLimited, wantCode: 429, wantRequests: 1},
+		{name: "401 is not retried", replies: []fakeReply{now(401)}, retries: 3, wantErr: true, wantIs: ErrAuth, wantCode: 401, wantRequests: 1},
+		{name: "400 is not retried", replies: []fakeReply{now(400), ok}, retries: 3, wantErr: true, wantCode: 400, wantRequests: 1},
+		{name: "504 is a timeout", replies: []fakeReply{now(504)}, retries: 0, wantErr: true, wantIs: ErrTimeout, wantCode: 504, wantRequests: 1},
+		{
+			name:         "Retry-After past the deadline is not waited for",
+			replies:      []fakeReply{{status: 429, retryAfter: "120"}, ok},
+			retries:      3,
+			timeout:      time.Second,
+			wantErr:      true,
+			wantIs:       ErrRateLimited,
+			wantCode:     429,
+			wantRequests: 1,
+		},
+		{
+			name:         "Retry-After as an HTTP date in the past",
+			replies:      []fakeReply{{status: 503, retryAfter: "Mon, 02 Jan 2006 15:04:05 GMT"}, ok},
+			retries:      1,
+			wantRequests: 2,
+		},
+		{
+			name:         "network timeout",
+			replies:      []fakeReply{{err: timeoutError{}}},
+			retries:      3,
+			wantErr:      true,
+			wantIs:       ErrTimeout,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{replies: tt.replies}
+			c := caller{client: client, timeout: tt.timeout, retries: tt.retries}
+			ctx, cancel := c.deadline(context.Background())
+			defer cancel()
+
+			resp, err := c.send(ctx, "POST", "http://backend/api", []byte(`{}`), http.Header{"Content-Type": {"application/json"}})
+			if client.requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", client.requests, tt.wantRequests)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("send() error = %v", err)
+				}
+				resp.Body.Close()
+				return
+			}
+			if err == nil {
+				t.Fatal("send() succeeded, want an error")
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("send() error = %v, want %v", err, tt.wantIs)
+			}
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) != (tt.wantCode != 0) || (statusErr != nil && statusErr.Code != tt.wantCode) {
+				t.Errorf("send() error = %v, want status %d", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestCallerSendCanceledWhileWaiting(t *testing.T) {
+	client := &fakeClient{replies: []fakeReply{{status: 503, retryAfter: "60"}}}
+	c := caller{client: client, retries: 3}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := c.send(ctx, "GET", "http://backend/api", nil, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("send() error = %v, want context.Canceled", err)
+	}
+	if errors.Is(err, ErrTimeout) {
+		t.Errorf("send() error = %v, cancellation is no timeout", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"7", 7 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"Mon, 02 Jun 2025 12:00:30 GMT", 30 * time.Second, true},
+		{"Mon, 02 Jun 2025 11:00:00 GMT", 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 40; attempt++ {
+		full := retryMaxDelay
+		if attempt < 16 {
+			full = min(retryBaseDelay<<attempt, retryMaxDelay)
+		}
+		for i := 0; i < 20; i++ {
+			if got := backoff(attempt); got < full/2 || got > full {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", attempt, got, full/2, full)
+			}
+		}
+	}
+}
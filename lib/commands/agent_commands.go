@@ -0,0 +1,301 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mytodo/lib/agent"
+	"mytodo/lib/config"
+	"mytodo/lib/output"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/utils"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// NewConfiguredAgent creates the agent a command uses, from the agent.*
+// settings, agent.commands.<command>.* overrides and the --agent and --model
+// flags.
+func NewConfiguredAgent(command string) (agent.LlmAgent, error) {
+	get := func(name string) string {
+		value, _ := config.LookupFor(command, name)
+		return value
+	}
+
+	s := agent.Settings{
+		Backend:  get("agent.backend"),
+		Model:    get("agent.model"),
+		Endpoint: get("agent.endpoint"),
+	}
+	if value := get("agent.temperature"); value != "" {
+		t, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("agent.temperature: %w", err)
+		}
+		s.Temperature = &t
+	}
+	if value := get("agent.max_tokens"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("agent.max_tokens: %w", err)
+		}
+		s.MaxTokens = n
+	}
+	if value := get("agent.timeout"); value != "" && value != "0" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("agent.timeout: %w", err)
+		}
+		s.Timeout = d
+	}
+	if value := get("agent.retries"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("agent.retries: %w", err)
+		}
+		s.Retries = &n
+	}
+
+	if s.Backend != agent.BackendOllama {
+		var err error
+		if s.APIKey, err = utils.GetOpenAIToken(); err != nil {
+			return nil, err
+		}
+	}
+	return agent.New(s)
+}
+
+// aiContext returns the context of an AI request, which Ctrl-C cancels.
+// Outside AI requests Ctrl-C keeps stopping mytodo straight away.
+func aiContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(cmd.Context(), os.Interrupt)
+}
+
+// agentError reports a failed AI request as what went wrong, with a hint for
+// the kinds of failure the agent tells apart.
+func agentError(what string, err error) error {
+	var hint string
+	switch {
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%s: canceled", what)
+	case errors.Is(err, agent.ErrRateLimited):
+		hint = "the AI backend is rate limiting requests; wait a minute and try again"
+	case errors.Is(err, agent.ErrAuth):
+		hint = "check the API key with 'mytodo auth status'"
+	case errors.Is(err, agent.ErrTimeout):
+		timeout, _ := config.LookupFor(agentCommand, "agent.timeout")
+		hint = fmt.Sprintf("no reply within agent.timeout (%s); raise it or pick a faster model with --model", timeout)
+	case errors.Is(err, agent.ErrUnavailable):
+		hint = "the AI backend is failing; try again later or switch with --agent"
+	case errors.Is(err, agent.ErrBadResponse):
+		hint = "the model's reply could not be used; try again or pick another model with --model"
+	default:
+		return fmt.Errorf("%s: %w", what, err)
+	}
+	return fmt.Errorf("%s: %w\nHint: %s", what, err, hint)
+}
+
+// streamReply sends a prompt to the agent and writes the reply to w as it
+// arrives, without its leading blank space, ending it with a newline. It
+// returns the whole reply.
+func streamReply(cmd *cobra.Command, w io.Writer, prompt string) (string, error) {
+	ctx, stop := aiContext(cmd)
+	defer stop()
+
+	started := false
+	reply, err := llmAgent.Stream(ctx, prompt, func(token string) error {
+		if !started {
+			if token = strings.TrimLeft(token, " \t\r\n"); token == "" {
+				return nil
+			}
+			started = true
+		}
+		_, err := io.WriteString(w, token)
+		return err
+	})
+	if started && !strings.HasSuffix(reply, "\n") {
+		fmt.Fprintln(w)
+	}
+	return reply, err
+}
+
+// waitingFor tells someone at the terminal that a buffered prompt is under
+// way, since nothing shows until the whole reply is in.
+func waitingFor(what string) {
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		fmt.Fprintf(os.Stderr, "⏳ Waiting for %s from the AI backend…\n", what)
+	}
+}
+
+// askAgent asks the agent a yes/no question.
+func askAgent(cmd *cobra.Command, question string) (bool, error) {
+	ctx, stop := aiContext(cmd)
+	defer stop()
+
+	yes, err := llmAgent.AskConfirmation(ctx, question)
+	if err != nil {
+		return false, agentError("AI confirmation", err)
+	}
+	return yes, nil
+}
+
+// generatedTasks is the reply generateTasks asks for; structured output
+// modes want an object at the root.
+type generatedTasks struct {
+	Tasks []tasklist.Task `json:"tasks"`
+}
+
+var generatedTasksSchema = agent.Schema{
+	Name: "tasks",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"tasks": map[string]interface{}{"type": "array", "items": generatedTaskSchema, "minItems": 1},
+		},
+		"required":             []string{"tasks"},
+		"additionalProperties": false,
+	},
+}
+
+// generatedTaskSchema is TaskSchema without the fields the task list sets,
+// the ID and timestamps, and without those naming other tasks, which the
+// model cannot know the IDs of.
+var generatedTaskSchema = schemaWithout(tasklist.TaskSchema, "id", "parent", "depends", "created_at", "completed_at", "updated_at")
+
+// schemaWithout returns a copy of an object schema without the named
+// properties.
+func schemaWithout(schema map[string]interface{}, names ...string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for name, property := range schema["properties"].(map[string]interface{}) {
+		properties[name] = property
+	}
+	for _, name := range names {
+		delete(properties, name)
+	}
+	result := map[string]interface{}{}
+	for key, value := range schema {
+		result[key] = value
+	}
+	result["properties"] = properties
+	return result
+}
+
+// generateTasks asks the agent for new tasks, checked against the task
+// schema. IDs, timestamps and references to other tasks are left for the
+// task list and the user to set.
+func generateTasks(cmd *cobra.Command, prompt string) ([]tasklist.Task, error) {
+	ctx, stop := aiContext(cmd)
+	defer stop()
+
+	var reply generatedTasks
+	err := agent.PromptInto(ctx, llmAgent, prompt, generatedTasksSchema, &reply, func(v interface{}) error {
+		tasks := v.(*generatedTasks).Tasks
+		if len(tasks) == 0 {
+			return fmt.Errorf("no tasks")
+		}
+		for i := range tasks {
+			if err := tasks[i].Validate(); err != nil {
+				return fmt.Errorf("tasks[%d]: %w", i, err)
+			}
+		}
+		return nil
+	}, agent.DefaultAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range reply.Tasks {
+		task := &reply.Tasks[i]
+		task.ID, task.ParentID, task.DependsOn = "", "", nil
+		task.CreatedAt, task.UpdatedAt, task.CompletedAt = nil, nil, nil
+	}
+	return reply.Tasks, nil
+}
+
+func NewAgentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Inspect the AI backend",
+		Long: `The AI backend and how it is asked come from the agent.* settings (see
+"mytodo config"): backend, model, endpoint, temperature, max_tokens, timeout
+and retries.
+A command can override them under agent.commands.<command>, e.g.
+
+  agent:
+    model: gpt-4.1
+    commands:
+      list:                 # list --summary
+        model: gpt-4.1-mini
+
+--agent and --model override both for a single run.`,
+	}
+
+	cmd.AddCommand(newAgentModelsCmd())
+	return cmd
+}
+
+func newAgentModelsCmd() *cobra.Command {
+	var command string
+
+	cmd := &cobra.Command{
+		Use:   "models",
+		Short: "List the models the selected backend offers; * marks the configured one",
+		Long: `List the models the selected backend offers: those pulled into the Ollama
+server, or those the OpenAI API key can use. * marks the model mytodo uses.
+
+Example: mytodo agent models
+Example: mytodo agent models --agent ollama
+Example: mytodo agent models --for standup`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := NewConfiguredAgent(command)
+			if err != nil {
+				return err
+			}
+			ctx, stop := aiContext(cmd)
+			defer stop()
+			models, err := a.Models(ctx)
+			if err != nil {
+				return agentError("listing models", err)
+			}
+
+			backend, _ := config.LookupFor(command, "agent.backend")
+			current, _ := config.LookupFor(command, "agent.model")
+			if current == "" {
+				current = agent.DefaultOpenAIModel
+				if backend == agent.BackendOllama {
+					current = agent.DefaultOllamaModel
+				}
+			}
+
+			if outputFormat.Structured() {
+				if models == nil {
+					models = []string{}
+				}
+				return output.Encode(os.Stdout, outputFormat, models)
+			}
+			rows := make([][]string, 0, len(models))
+			for _, model := range models {
+				mark := ""
+				if model == current {
+					mark = "*"
+				}
+				rows = append(rows, []string{mark, model})
+			}
+			if outputFormat == output.Plain {
+				fmt.Printf("Backend: %s\n\n", backend)
+			}
+			return output.WriteTable(os.Stdout, []string{"", "model"}, rows)
+		},
+	}
+
+	cmd.Flags().StringVar(&command, "for", "", "Show the backend and model configured for this command")
+	return cmd
+}
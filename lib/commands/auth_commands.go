@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"mytodo/lib/config"
+	"mytodo/lib/credentials"
+	"mytodo/lib/output"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// serviceSettings maps a credentials service to its secret setting.
+var serviceSettings = map[string]string{
+	credentials.Jira:   "jira.token",
+	credentials.Quip:   "quip.token",
+	credentials.OpenAI: "agent.api_key",
+}
+
+func NewAuthCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Keep JIRA, Quip and OpenAI secrets in an encrypted credentials file",
+		Long: `Secrets are looked up in this order, and the first one found is used:
+
+  1. the setting: jira.token, quip.token or agent.api_key, from the config files
+     or JIRA_TOKEN, QUIP_TOKEN, OPEN_AI_API_KEY
+  2. a credential helper: the command in jira.token_command, quip.token_command
+     or agent.api_key_command, e.g. "pass show jira", which prints the secret
+  3. the credentials file, written by "mytodo auth login"
+
+The credentials file is encrypted like the task file (see "mytodo encrypt"):
+its passphrase is read from MYTODO_KEY_FILE, MYTODO_PASSPHRASE or a prompt.
+JIRA and Quip secrets are stored for the active profile (see --profile).
+Resolved secrets are kept in memory for the running command only.
+
+Example: mytodo config set jira.token_command "pass show jira"
+Example: mytodo auth login jira
+Example: mytodo --profile partner auth login jira`,
+	}
+
+	cmd.AddCommand(newAuthLoginCmd(), newAuthLogoutCmd(), newAuthStatusCmd())
+	return cmd
+}
+
+// serviceProfile returns the profile a service's secret is stored under: the
+// active one for JIRA and Quip, none for OpenAI.
+func serviceProfile(service string) string {
+	if key, err := config.FindKey(serviceSettings[service]); err == nil && key.Profile {
+		return config.ActiveProfile()
+	}
+	return ""
+}
+
+func serviceArg(args []string) (string, error) {
+	if _, ok := serviceSettings[args[0]]; !ok {
+		return "", fmt.Errorf("unknown service %q: use %s", args[0], strings.Join(credentials.Services, ", "))
+	}
+	return args[0], nil
+}
+
+func describeService(service string) string {
+	if profile := serviceProfile(service); profile != "" {
+		return fmt.Sprintf("%s (profile %s)", service, profile)
+	}
+	return service
+}
+
+var completeService = cobra.FixedCompletions(credentials.Services, cobra.ShellCompDirectiveNoFileComp)
+
+func newAuthLoginCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "login <jira|quip|openai>",
+		Short: "Store a secret in the credentials file",
+		Long: `Store a secret in the credentials file. The secret is read without echo from
+the terminal, or as the first line of stdin when it is not a terminal.
+
+Example: mytodo auth login jira
+Example: pass show quip | mytodo auth login quip`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeService,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service, err := serviceArg(args)
+			if err != nil {
+				return err
+			}
+			secret, err := readSecret(fmt.Sprintf("%s secret: ", describeService(service)))
+			if err != nil {
+				return err
+			}
+			if secret == "" {
+				return fmt.Errorf("empty secret")
+			}
+
+			store, err := credentials.Open()
+			if err != nil {
+				return err
+			}
+			store.Set(service, serviceProfile(service), secret)
+			if err := store.Save(); err != nil {
+				return fmt.Errorf("saving credentials: %w", err)
+			}
+
+			fmt.Printf("✅ Stored the %s secret\n", describeService(service))
+			if value, source := config.Lookup(serviceSettings[service]); value != "" {
+				fmt.Printf("Note: %s is set (%s) and takes precedence.\n", serviceSettings[service], source)
+			} else if command := config.Get(serviceSettings[service] + "_command"); command != "" {
+				fmt.Printf("Note: %s_command is set and takes precedence.\n", serviceSettings[service])
+			}
+			return nil
+		},
+	}
+}
+
+func newAuthLogoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "logout <jira|quip|openai>",
+		Short:             "Remove a secret from the credentials file",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeService,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service, err := serviceArg(args)
+			if err != nil {
+				return err
+			}
+			if !credentials.Exists() {
+				return fmt.Errorf("no %s secret stored", describeService(service))
+			}
+			store, err := credentials.Open()
+			if err != nil {
+				return err
+			}
+			if !store.Delete(service, serviceProfile(service)) {
+				return fmt.Errorf("no %s secret stored", describeService(service))
+			}
+			if err := store.Save(); err != nil {
+				return fmt.Errorf("saving credentials: %w", err)
+			}
+			fmt.Printf("✅ Removed the %s secret\n", describeService(service))
+			return nil
+		},
+	}
+}
+
+func newAuthStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show where each secret comes from, without showing it",
+		Long: `Show where the secret of each service comes from for the active profile.
+Helpers are not run; the credentials file is unlocked when it exists and no
+setting or helper comes first.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var store *credentials.Store
+			var rows [][]string
+			for _, service := range credentials.Services {
+				name := serviceSettings[service]
+				var status string
+				if value, source := config.Lookup(name); value != "" {
+					status = fmt.Sprintf("%s (%s)", name, source)
+				} else if command := config.Get(name + "_command"); command != "" {
+					status = fmt.Sprintf("helper: %s", command)
+				} else if credentials.Exists() {
+					if store == nil {
+						var err error
+						if store, err = credentials.Open(); err != nil {
+							return err
+						}
+					}
+					if _, ok := store.Get(service, serviceProfile(service)); ok {
+						status = "credentials file"
+					}
+				}
+				if status == "" {
+					status = "not set"
+				}
+				rows = append(rows, []string{describeService(service), status})
+			}
+			if err := output.WriteTable(os.Stdout, []string{"service", "secret"}, rows); err != nil {
+				return err
+			}
+
+			if path, err := credentials.Path(); err == nil && credentials.Exists() {
+				fmt.Printf("\nCredentials file: %s\n", path)
+				if store != nil {
+					fmt.Printf("Stored: %s\n", strings.Join(store.Entries(), ", "))
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// readSecret reads a secret without echo from the terminal, or the first line
+// of stdin when it is not a terminal.
+func readSecret(prompt string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("reading the secret from stdin: %w", err)
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading the secret: %w", err)
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
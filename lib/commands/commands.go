@@ -7,12 +7,17 @@ import (
 	"io"
 	"io/ioutil"
 	"mytodo/lib/agent"
+	"mytodo/lib/config"
+	"mytodo/lib/listformat"
+	"mytodo/lib/output"
 	"mytodo/lib/tasklist"
 	"mytodo/lib/utils"
 	"strings"
+	"sync"
 
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -21,45 +26,99 @@ import (
 var (
 	MasterTasks *tasklist.TaskList
 	llmAgent    agent.LlmAgent
+	newAgent    func(command string) (agent.LlmAgent, error)
+	agentOnce   sync.Once
+	// agentCommand is the running command, which can have its own agent
+	// settings.
+	agentCommand string
+	configErr    error
+
+	// Version is reported to API clients; override with -ldflags "-X mytodo/lib/commands.Version=..."
+	Version = "dev"
 )
 
 func SetMasterTasks(t *tasklist.TaskList) {
 	MasterTasks = t
 }
 
-// SetAgent allows cmd/main to inject the LLM agent into the commands package.
-func SetAgent(a agent.LlmAgent) {
-	llmAgent = a
+// SetAgentFactory allows cmd/main to inject the LLM agent into the commands
+// package. The agent is created on first use, for the running command, so
+// that only AI features resolve the API key, which can mean running a
+// credential helper.
+func SetAgentFactory(f func(command string) (agent.LlmAgent, error)) {
+	newAgent = f
 }
 
-func nicePrint(writer io.Writer, tasks []tasklist.Task) error {
+// SetConfigError records a problem found while loading the config files. It
+// is reported by every command but "mytodo config".
+func SetConfigError(err error) {
+	configErr = err
+}
+
+// nicePrint writes the tasks for which visible returns true (all of them when
+// visible is nil). Task numbers always refer to the position in tasks, so they
+// stay valid for done/edit/... even when some tasks are hidden.
+func nicePrint(writer io.Writer, tasks []tasklist.Task, visible func(task *tasklist.Task) bool) error {
 	// Define styled printers
 	success := color.New(color.FgGreen, color.Bold).Sprintf
 	info := color.New(color.FgCyan).Sprintf
+	snoozed := color.New(color.FgHiBlack).Sprintf
 
-	commentPrinter := func(comments []string) {
+	commentPrinter := func(comments []tasklist.Comment) {
 		for _, comment := range comments {
-			fmt.Fprintf(writer, "\t\t- %s\n", comment)
+			fmt.Fprintf(writer, "\t\t- %s\n", comment.Text)
 		}
 	}
 
+	now := time.Now()
+
 	// Write each task with appropriate style and icon
 	for index, task := range tasks {
+		if visible != nil && !visible(&task) {
+			continue
+		}
+
 		var formatted string
-		switch task.Done {
-		case true:
+		switch {
+		case task.Done:
 			formatted = success("✔\t%d. %s: %s", index, task.Content, "Completed")
-		case false:
+		case task.IsSnoozed(now):
+			formatted = snoozed("💤\t%d. %s: Snoozed until %s", index, task.Content, utils.FormatDate(*task.Snoozed))
+		default:
 			formatted = info("⏳\t%d. %s: %s", index, task.Content, "Pending")
 		}
+		if task.ParentID != "" {
+			formatted = "  ↳ " + formatted
+		}
 		if _, err := fmt.Fprintln(writer, formatted); err != nil {
 			return fmt.Errorf("failed to write task %s: %w", task.Content, err)
 		}
 		commentPrinter(task.Comments)
+		for _, link := range task.Links {
+			fmt.Fprintf(writer, "\t\t🔗 %s\n", link)
+		}
 	}
 	return nil
 }
 
+// agentAvailable reports whether AI features are enabled and the configured
+// backend could be set up.
+func agentAvailable() bool {
+	if !utils.AgentEnabled() || newAgent == nil {
+		return false
+	}
+	agentOnce.Do(func() {
+		a, err := newAgent(agentCommand)
+		if err != nil {
+			// Only AI features need the agent; everything else keeps working.
+			fmt.Fprintln(os.Stderr, "Warning:", err)
+			return
+		}
+		llmAgent = a
+	})
+	return llmAgent != nil
+}
+
 func GetTaskList() *tasklist.TaskList {
 	return MasterTasks
 }
@@ -73,49 +132,98 @@ func PrepareCommands() *cobra.Command {
 	var verbose bool
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 
+	var format string
+	rootCmd.PersistentFlags().StringVarP(&format, "output", "o", "", "Output format: plain, table, json, yaml (default: the display.output setting, plain)")
+	rootCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]cobra.Completion{"plain", "table", "json", "yaml"}, cobra.ShellCompDirectiveNoFileComp))
+
+	var profile string
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Profile for JIRA and Quip (default: the profile setting)")
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfile)
+
+	var agentBackend, agentModel string
+	rootCmd.PersistentFlags().StringVar(&agentBackend, "agent", "", "AI backend for this run: openai or ollama (default: the agent.backend setting)")
+	rootCmd.PersistentFlags().StringVar(&agentModel, "model", "", "AI model for this run (default: the agent.model setting)")
+	rootCmd.RegisterFlagCompletionFunc("agent", cobra.FixedCompletions([]cobra.Completion{agent.BackendOpenAI, agent.BackendOllama}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if configErr != nil && !isConfigCommand(cmd) {
+			return fmt.Errorf("config: %w (fix it with 'mytodo config edit' or 'mytodo config unset')", configErr)
+		}
+
+		if cmd.Flags().Changed("output") {
+			config.SetFlag("display.output", format)
+		}
+		if cmd.Flags().Changed("profile") {
+			config.SetFlag("profile", profile)
+		}
+		if cmd.Flags().Changed("agent") {
+			if agentBackend != agent.BackendOpenAI && agentBackend != agent.BackendOllama {
+				return fmt.Errorf("unknown --agent %q: use openai or ollama", agentBackend)
+			}
+			config.SetFlag("agent.backend", agentBackend)
+		}
+		if cmd.Flags().Changed("model") {
+			config.SetFlag("agent.model", agentModel)
+		}
+		agentCommand = cmd.Name()
+		if err := config.CheckProfile(); err != nil && !isConfigCommand(cmd) {
+			return err
+		}
+		f, err := output.Parse(config.Get("display.output"))
+		if err != nil {
+			return err
+		}
+		outputFormat = f
+		switch config.Get("display.color") {
+		case "always":
+			color.NoColor = false
+		case "never":
+			color.NoColor = true
+		}
+		f.Apply()
+		return nil
+	}
+
 	addCmd := createAddCmd(verbose)
 
 	listCmd := createListCmd(verbose)
 
 	removeCommand := &cobra.Command{
-		Use:   "remove [task number]",
-		Short: "Remove a task by its number",
-		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			if GetTaskList().NumberOfTasks() == 0 {
-				fmt.Println("No tasks to remove.")
-				return
+		Use:               "remove [task number]",
+		Short:             "Remove a task by its number",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeTaskNumber(nil),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if emptyList("No tasks to remove.") {
+				return nil
 			}
-			defer printToStdout()
 
 			id, err := indexFromArgument(args)
 			if err != nil {
-				return
+				return finishChange(id, nil, err)
 			}
 
 			if verbose {
 				fmt.Println("Removing task with ID:", id)
 			}
 
-			GetTaskList().RemoveTask(id)
-
+			t := GetTaskList().GetTask(id)
+			return finishChange(id, t, GetTaskList().RemoveTask(id))
 		},
 	}
 
 	doneCommand := &cobra.Command{
-		Use:   "done [task number]",
-		Short: "Mark a task as done by its number",
-		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			if len(GetTaskList().Tasks) == 0 {
-				fmt.Println("No tasks to mark as done.")
-				return
+		Use:               "done [task number]",
+		Short:             "Mark a task as done by its number",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeTaskNumber(isPending),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if emptyList("No tasks to mark as done.") {
+				return nil
 			}
-			defer printToStdout()
 
 			id, err := indexFromArgument(args)
 			if err != nil {
-				return
+				return finishChange(id, nil, err)
 			}
 
 			if verbose {
@@ -124,25 +232,23 @@ func PrepareCommands() *cobra.Command {
 
 			t := GetTaskList().GetTask(id)
 			t.Done = true
-			GetTaskList().ReplaceTask(id, t)
-
+			return finishChange(id, t, GetTaskList().ReplaceTask(id, t))
 		},
 	}
 
 	undoneCommand := &cobra.Command{
-		Use:   "undone [task number]",
-		Short: "Mark a task as not done by its number",
-		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			if len(GetTaskList().Tasks) == 0 {
-				fmt.Println("No tasks to mark as not done.")
-				return
+		Use:               "undone [task number]",
+		Short:             "Mark a task as not done by its number",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeTaskNumber(isDone),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if emptyList("No tasks to mark as not done.") {
+				return nil
 			}
-			defer printToStdout()
 
 			id, err := indexFromArgument(args)
 			if err != nil {
-				return
+				return finishChange(id, nil, err)
 			}
 
 			if verbose {
@@ -151,24 +257,23 @@ func PrepareCommands() *cobra.Command {
 
 			t := GetTaskList().GetTask(id)
 			t.Done = false
-			GetTaskList().ReplaceTask(id, t)
+			return finishChange(id, t, GetTaskList().ReplaceTask(id, t))
 		},
 	}
 
 	editCommand := &cobra.Command{
-		Use:   "edit [task number] [new content]",
-		Short: "Edit a task's content by its number",
-		Args:  cobra.MinimumNArgs(2),
-		Run: func(cmd *cobra.Command, args []string) {
-			if len(GetTaskList().Tasks) == 0 {
-				fmt.Println("No tasks to edit.")
-				return
+		Use:               "edit [task number] [new content]",
+		Short:             "Edit a task's content by its number",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeTaskNumber(nil),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if emptyList("No tasks to edit.") {
+				return nil
 			}
-			defer printToStdout()
 
 			id, err := indexFromArgument(args)
 			if err != nil {
-				return
+				return finishChange(id, nil, err)
 			}
 
 			newContent := args[1]
@@ -178,37 +283,83 @@ func PrepareCommands() *cobra.Command {
 
 			t := GetTaskList().GetTask(id)
 			t.Content = newContent
-			GetTaskList().ReplaceTask(id, t)
+			return finishChange(id, t, GetTaskList().ReplaceTask(id, t))
 		},
 	}
 
 	addComment := &cobra.Command{
-		Use:   "cm [task number] [comment]",
-		Short: "Add a comment to a task by its number",
-		Args:  cobra.MinimumNArgs(2),
-		Run: func(cmd *cobra.Command, args []string) {
-			if GetTaskList().NumberOfTasks() == 0 {
-				fmt.Println("No tasks to comment on.")
-				return
+		Use:               "cm [task number] [comment]",
+		Short:             "Add a comment to a task by its number",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeTaskNumber(nil),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if emptyList("No tasks to comment on.") {
+				return nil
 			}
-			defer printToStdout()
 
 			id, err := indexFromArgument(args)
 			if err != nil {
-				return
+				return finishChange(id, nil, err)
 			}
 
 			comment := args[1]
-			GetTaskList().AddComment(id, comment)
+			err = GetTaskList().AddComment(id, comment)
+			return finishChange(id, GetTaskList().GetTask(id), err)
 		},
 	}
 
+	snoozeCmd := createSnoozeCmd(verbose)
+
+	unsnoozeCmd := createUnsnoozeCmd(verbose)
+
 	jiraSummaryCmd := NewJiraSummaryCmd()
 
 	jiraCreateCmd := NewJiraCreateCmd()
 
 	jiraEpicTrackerCmd := NewJiraEpicTrackerCmd()
 
+	exportCmd := NewExportCmd()
+
+	importCmd := NewImportCmd()
+
+	syncCmd := NewSyncCmd()
+
+	serveCmd := NewServeCmd()
+
+	mcpCmd := NewMcpCmd()
+
+	encryptCmd := NewEncryptCmd()
+
+	decryptCmd := NewDecryptCmd()
+
+	unlockCmd := NewUnlockCmd()
+
+	statsCmd := NewStatsCmd()
+
+	uiCmd := NewUiCmd()
+
+	templateCmd := NewTemplateCmd()
+
+	attachCmd := NewAttachCmd()
+
+	detachCmd := NewDetachCmd()
+
+	openCmd := NewOpenCmd()
+
+	hooksCmd := NewHooksCmd()
+
+	pluginsCmd := NewPluginsCmd()
+
+	standupCmd := NewStandupCmd()
+
+	reviewCmd := NewReviewCmd()
+
+	configCmd := NewConfigCmd()
+
+	authCmd := NewAuthCmd()
+
+	agentCmd := NewAgentCmd()
+
 	rootCmd.AddCommand(
 		addCmd,
 		listCmd,
@@ -217,9 +368,32 @@ func PrepareCommands() *cobra.Command {
 		undoneCommand,
 		editCommand,
 		addComment,
+		snoozeCmd,
+		unsnoozeCmd,
 		jiraSummaryCmd,
 		jiraCreateCmd,
 		jiraEpicTrackerCmd,
+		exportCmd,
+		importCmd,
+		syncCmd,
+		serveCmd,
+		mcpCmd,
+		encryptCmd,
+		decryptCmd,
+		unlockCmd,
+		statsCmd,
+		uiCmd,
+		templateCmd,
+		attachCmd,
+		detachCmd,
+		openCmd,
+		hooksCmd,
+		pluginsCmd,
+		standupCmd,
+		reviewCmd,
+		configCmd,
+		authCmd,
+		agentCmd,
 	)
 	return rootCmd
 }
@@ -227,33 +401,127 @@ func PrepareCommands() *cobra.Command {
 func indexFromArgument(args []string) (int, error) {
 	id, err := strconv.Atoi(args[0])
 	if err != nil || id < 0 || id >= len(GetTaskList().Tasks) {
-		fmt.Println("Invalid task number.")
-		return -1, fmt.Errorf("invalid task number")
+		return -1, tasklist.ErrInvalidIndex
 	}
 	return id, nil
 }
 
+// printToStdout prints the active tasks, leaving out snoozed ones.
 func printToStdout() {
 	tasks := GetTaskList().GetAllTasks()
-	nicePrint(os.Stdout, tasks)
+	now := time.Now()
+
+	hidden := 0
+	nicePrint(os.Stdout, tasks, func(task *tasklist.Task) bool {
+		if task.IsSnoozed(now) {
+			hidden++
+			return false
+		}
+		return true
+	})
+
+	if hidden > 0 {
+		fmt.Printf("(%d snoozed task(s) hidden, see 'mytodo list --snoozed')\n", hidden)
+	}
+}
+
+// printWithTemplate writes each listed task through a template.
+func printWithTemplate(text string, showSnoozed bool) error {
+	tmpl, err := listformat.Parse(text)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	for i, task := range GetTaskList().GetAllTasks() {
+		if task.IsSnoozed(now) != showSnoozed {
+			continue
+		}
+		sb.Reset()
+		if err := tmpl.Execute(&sb, listformat.Task{Number: i, Task: task}); err != nil {
+			return fmt.Errorf("task %d: %w", i, err)
+		}
+		line := sb.String()
+		if line == "" {
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		fmt.Print(line)
+	}
+	return nil
 }
 
 func createListCmd(verbose bool) *cobra.Command {
 	var summary bool
+	var showSnoozed bool
+	var tmplText string
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all tasks",
+		Long: `List the tasks. Snoozed tasks are hidden unless --snoozed is given.
+
+--template formats each task with a Go template, given inline or as the name
+of a format: builtin (oneline, tmux, review) or a <name>.tmpl file in the
+mytodo/formats directory under your user config directory. Templates see the
+task fields (.Number, .ID, .Content, .Done, .Due, .Priority, .Tags, .Comments,
+.CreatedAt, ...) and these helpers:
+
+  red green yellow blue magenta cyan gray bold   color text
+  date relative ago                               format dates ("in 3d", "2w ago")
+  trunc N  pad N  join SEP  upper  lower          shape text
+
+Tasks whose template output is empty are skipped. The display.template
+setting (see 'mytodo config') gives a default for --template.
+
+Example: mytodo list --template '{{.Number}} {{if .Done}}✔{{end}} {{.Content}}'
+Example: mytodo list --template oneline`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if GetTaskList().NumberOfTasks() == 0 {
+			if tmplText == "" && outputFormat == output.Plain && !summary {
+				tmplText = config.Get("display.template")
+			}
+			if tmplText != "" {
+				if outputFormat != output.Plain {
+					return fmt.Errorf("--template only works with plain output")
+				}
+				return printWithTemplate(tmplText, showSnoozed)
+			}
+
+			if outputFormat != output.Plain {
+				if summary && outputFormat.Structured() {
+					return fmt.Errorf("--summary only works with plain or table output")
+				}
+				now := time.Now()
+				var tasks []numberedTask
+				for i, task := range GetTaskList().GetAllTasks() {
+					if task.IsSnoozed(now) == showSnoozed {
+						tasks = append(tasks, numberedTask{Number: i, Task: task})
+					}
+				}
+				if err := writeTasks(os.Stdout, tasks); err != nil {
+					return err
+				}
+			} else if GetTaskList().NumberOfTasks() == 0 {
 				fmt.Println("No tasks found.")
 				return nil
+			} else if showSnoozed {
+				now := time.Now()
+				return nicePrint(os.Stdout, GetTaskList().GetAllTasks(), func(task *tasklist.Task) bool {
+					return task.IsSnoozed(now)
+				})
+			} else {
+				// Print them to terminal directly
+				printToStdout()
 			}
 
-			// Print them to terminal directly
-			printToStdout()
-
 			// Summarize if set
 			if summary {
+				if !agentAvailable() {
+					return fmt.Errorf("--summary needs an AI backend (see 'mytodo config')")
+				}
+
 				// 1️⃣  Gather all tasks
 				tasks := GetTaskList().GetAllTasks()
 
@@ -268,30 +536,95 @@ func createListCmd(verbose bool) *cobra.Command {
 		%s
 		Summarize the above list in one concise sentence.`, string(b))
 
-				sResp, err := llmAgent.Prompt(summaryPrompt)
-				if err != nil {
-					return fmt.Errorf("LLM summary prompt error: %w", err)
-				}
-				summaryText, err := sResp.GetResponse()
-				if err != nil {
-					return fmt.Errorf("reading LLM summary: %w", err)
+				fmt.Print("\n🔍 Summary: ")
+				if _, err := streamReply(cmd, os.Stdout, summaryPrompt); err != nil {
+					return agentError("LLM summary prompt error", err)
 				}
-				fmt.Println("\n🔍 Summary:", strings.TrimSpace(summaryText))
 			}
 
 			return nil
 		},
 	}
 	listCmd.Flags().BoolVarP(&summary, "summary", "s", false, "Show a short summary of the tasks")
+	listCmd.Flags().BoolVar(&showSnoozed, "snoozed", false, "Show only snoozed tasks and when they come back")
+	listCmd.Flags().StringVarP(&tmplText, "template", "t", "", "Format each task with a Go template or a named format")
+	listCmd.RegisterFlagCompletionFunc("template", func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return listformat.Names(), cobra.ShellCompDirectiveNoFileComp
+	})
 	return listCmd
 }
 
+func createSnoozeCmd(verbose bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   "snooze [task number] [when]",
+		Short: "Hide a task from the list until a date",
+		Long: `Hide a task from the list until the given date; it comes back on its own.
+
+"when" accepts tomorrow, weekday names (friday), offsets (3d, 2w, 1m) or YYYY-MM-DD.
+
+Example: mytodo snooze 3 2025-07-01
+Example: mytodo snooze 3 2w`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeTaskNumber(isPending),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if emptyList("No tasks to snooze.") {
+				return nil
+			}
+
+			id, err := indexFromArgument(args)
+			if err != nil {
+				return finishChange(id, nil, err)
+			}
+
+			until, err := utils.ParseWhen(args[1], time.Now())
+			if err != nil {
+				return finishChange(id, nil, err)
+			}
+
+			if verbose {
+				fmt.Println("Snoozing task with ID:", id, "until:", utils.FormatDate(until))
+			}
+
+			t := GetTaskList().GetTask(id)
+			t.Snoozed = &until
+			return finishChange(id, t, GetTaskList().ReplaceTask(id, t))
+		},
+	}
+}
+
+func createUnsnoozeCmd(verbose bool) *cobra.Command {
+	return &cobra.Command{
+		Use:               "unsnooze [task number]",
+		Short:             "Bring a snoozed task back to the list now",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeTaskNumber(isSnoozed),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if emptyList("No tasks to unsnooze.") {
+				return nil
+			}
+
+			id, err := indexFromArgument(args)
+			if err != nil {
+				return finishChange(id, nil, err)
+			}
+
+			if verbose {
+				fmt.Println("Unsnoozing task with ID:", id)
+			}
+
+			t := GetTaskList().GetTask(id)
+			t.Snoozed = nil
+			return finishChange(id, t, GetTaskList().ReplaceTask(id, t))
+		},
+	}
+}
+
 func createAddCmd(verbose bool) *cobra.Command {
 	return &cobra.Command{
 		Use:   "add",
 		Short: "Create tasks from free‑form text via the LLM",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !utils.AgentEnabled() {
+			if !agentAvailable() {
 				// default to no AI mode
 				todo := args[0]
 				if verbose {
@@ -303,8 +636,13 @@ func createAddCmd(verbose bool) *cobra.Command {
 					Done:    false,
 				}
 
-				GetTaskList().AddTask(&task)
-				return nil
+				if err := GetTaskList().AddTask(&task); err != nil {
+					return err
+				}
+				if outputFormat == output.Plain {
+					return nil
+				}
+				return writeTasks(os.Stdout, []numberedTask{{Number: GetTaskList().NumberOfTasks() - 1, Task: task}})
 			}
 
 			// Otherwise use AI agent to generate tasks
@@ -330,35 +668,14 @@ func createAddCmd(verbose bool) *cobra.Command {
 			}
 
 			// ② Ask the LLM to transform it into structured tasks
-			response, err := llmAgent.Prompt(fmt.Sprintf(
-				`Please turn the following note into a JSON array of tasks.  
-Each task must have "content" (string) and "done" (boolean) fields.  
-No extra keys. No explaination.
-
-Note: "%s"`, rawInput))
-			if err != nil {
-				return fmt.Errorf("LLM prompt error: %w", err)
-			}
+			waitingFor("the tasks")
+			tasks, err := generateTasks(cmd, fmt.Sprintf(`Turn the following note into tasks. Set "content" and "done" for each;
+set due, priority (H, M or L), tags or recurrence only when the note says so.
+Today is %s.
 
-			// ③ Grab the raw JSON string from the response
-			resp, err := response.GetResponse()
+Note: "%s"`, time.Now().Format("Monday, 2006-01-02"), rawInput))
 			if err != nil {
-				return fmt.Errorf("parsing LLM output: %w", err)
-			}
-
-			// ④ Pretty‑print the raw JSON for the user (optional)
-			if verbose {
-				fmt.Println("LLM produced:")
-				fmt.Println(resp)
-			}
-
-			// Trimming the header and footer in the response, generated by the LLM if any
-			resp = utils.TrimResponse(resp)
-
-			// ⑤ Decode into Go structs
-			var tasks []tasklist.Task
-			if err := json.Unmarshal([]byte(resp), &tasks); err != nil {
-				return fmt.Errorf("unmarshaling tasks: %w", err)
+				return agentError("LLM prompt error", err)
 			}
 
 			// ── Confirmation & fine‑tune loop ─────────────────────────────────────────
@@ -366,11 +683,11 @@ Note: "%s"`, rawInput))
 			for !confirmed {
 				// Show tasks to the user
 				tasksJSON, _ := json.MarshalIndent(tasks, "", "  ")
-				fmt.Println("Generated tasks:")
-				fmt.Println(string(tasksJSON))
+				fmt.Fprintln(statusOut(), "Generated tasks:")
+				fmt.Fprintln(statusOut(), string(tasksJSON))
 
 				// Ask for confirmation
-				fmt.Print("Confirm adding these tasks? (yes/no). If no, please include how to make it better: ")
+				fmt.Fprint(statusOut(), "Confirm adding these tasks? (yes/no). If no, please include how to make it better: ")
 				reader := bufio.NewReader(os.Stdin)
 				answer, _ := reader.ReadString('\n')
 				answer = strings.TrimSpace(strings.ToLower(answer))
@@ -387,37 +704,27 @@ Original note: "%s"
 
 User feedback: "%s"
 
-With user feedback, please revise the task list to better reflect the note. Return a JSON array of tasks with "content" and "done" only, no extra keys, no explaination`, rawInput, answer)
-				fineResp, err := llmAgent.Prompt(finePrompt)
-				if err != nil {
-					return fmt.Errorf("LLM refine prompt error: %w", err)
-				}
-				fineOutput, err := fineResp.GetResponse()
-				if err != nil {
-					return fmt.Errorf("reading LLM refine response: %w", err)
-				}
-				fineOutput = utils.TrimResponse(fineOutput)
-
-				if verbose {
-					fmt.Println("LLM refinement:")
-					fmt.Println(fineOutput)
-				}
-
-				if err := json.Unmarshal([]byte(fineOutput), &tasks); err != nil {
-					return fmt.Errorf("unmarshaling refined tasks: %w", err)
+With user feedback, please revise the task list to better reflect the note.`, rawInput, answer)
+				waitingFor("the revised tasks")
+				if tasks, err = generateTasks(cmd, finePrompt); err != nil {
+					return agentError("LLM refine prompt error", err)
 				}
 			}
 			// ────────────────────────────────────────────────────────────────────────
 
-			// ⑥ Append each new task to the master list
+			// ③ Append each new task to the master list
 			master := GetTaskList()
+			var added []numberedTask
 			for _, t := range tasks {
-				master.AddTask(&t)
+				if err := master.AddTask(&t); err != nil {
+					return err
+				}
+				added = append(added, numberedTask{Number: master.NumberOfTasks() - 1, Task: t})
 			}
 
-			// ⑦ Persist the updated list
-			master.Save()
-
+			if outputFormat != output.Plain {
+				return writeTasks(os.Stdout, added)
+			}
 			fmt.Printf("✅ Added %d task(s) to the list.\n", len(tasks))
 			printToStdout()
 			return nil
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"mytodo/lib/jira"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/templates"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// IsCompletionRequest reports whether args invoke shell completion, which
+// runs on every <TAB> and so must neither prompt nor need an AI backend.
+func IsCompletionRequest(args []string) bool {
+	if len(args) < 2 {
+		return false
+	}
+	switch args[1] {
+	case cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd, "completion":
+		return true
+	}
+	return false
+}
+
+// completeTaskNumber suggests the numbers of the tasks accepted by include as
+// the first argument, described by their content.
+func completeTaskNumber(include func(task *tasklist.Task) bool) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return taskSuggestions(include, nil, toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	}
+}
+
+// completeTaskNumbers suggests task numbers for commands taking several
+// tasks, leaving out the ones already given.
+func completeTaskNumbers(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return taskSuggestions(nil, args, toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+func taskSuggestions(include func(task *tasklist.Task) bool, exclude []string, toComplete string) []cobra.Completion {
+	if GetTaskList() == nil {
+		return nil
+	}
+
+	var suggestions []cobra.Completion
+	for index, task := range GetTaskList().GetAllTasks() {
+		if include != nil && !include(&task) {
+			continue
+		}
+		number := strconv.Itoa(index)
+		if strings.HasPrefix(number, toComplete) && !slices.Contains(exclude, number) {
+			suggestions = append(suggestions, cobra.CompletionWithDesc(number, task.Content))
+		}
+	}
+	return suggestions
+}
+
+func isPending(task *tasklist.Task) bool {
+	return !task.Done
+}
+
+func isDone(task *tasklist.Task) bool {
+	return task.Done
+}
+
+func isSnoozed(task *tasklist.Task) bool {
+	return task.IsSnoozed(time.Now())
+}
+
+// completeEpicKey suggests epics from the recent epic cache.
+func completeEpicKey(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	epics, err := jira.RecentEpics()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var suggestions []cobra.Completion
+	for _, epic := range epics {
+		if strings.HasPrefix(strings.ToUpper(epic.Key), strings.ToUpper(toComplete)) {
+			suggestions = append(suggestions, cobra.CompletionWithDesc(epic.Key, epic.Summary))
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// completeTemplateName suggests the stored templates.
+func completeTemplateName(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, _ := templates.List()
+	var suggestions []cobra.Completion
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) {
+			suggestions = append(suggestions, name)
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
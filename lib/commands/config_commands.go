@@ -0,0 +1,300 @@
+package commands
+
+import (
+	"fmt"
+	"mytodo/lib/config"
+	"mytodo/lib/output"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show and change settings",
+		Long: `Settings are read from, lowest precedence first:
+
+  1. a project file, .mytodo.yaml in the current directory or a parent
+  2. the user file, mytodo/config.yaml under your user config directory
+  3. environment variables (JIRA_URL, OPEN_AI_API_KEY, ...)
+  4. command line flags
+
+Tokens, API keys, the *_command helpers, agent.endpoint and jira.url are only
+read from the user file and the environment: a project file comes with
+whatever repository is checked out, so it cannot run commands, supply secrets
+or send them to another host.
+
+Keys are dotted paths into the YAML file, e.g. jira.url is
+
+  jira:
+    url: https://company.atlassian.net
+
+Profiles keep the jira.* and quip.* settings of other accounts under
+profiles.<name>. The profile setting, MYTODO_PROFILE or --profile selects one;
+a project file's profile beats the user file's, so it gives its directory a
+default. The selected profile's settings are used as they are: the top-level
+ones and their environment variables are not mixed in.
+
+Example: mytodo config set agent.backend ollama
+Example: mytodo config set --project jira.project PROJ
+Example: mytodo config set profiles.partner.jira.url https://partner.atlassian.net
+Example: mytodo config set --project profile partner`,
+	}
+
+	cmd.AddCommand(newConfigGetCmd(), newConfigSetCmd(), newConfigUnsetCmd(), newConfigListCmd(), newConfigEditCmd(), newConfigProfilesCmd())
+	return cmd
+}
+
+// isConfigCommand reports whether cmd is "mytodo config" or one of its
+// subcommands.
+func isConfigCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "config" && c.Parent() != nil && !c.Parent().HasParent() {
+			return true
+		}
+	}
+	return false
+}
+
+func completeConfigKey(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]cobra.Completion, 0, len(config.Keys))
+	for _, key := range config.Keys {
+		names = append(names, cobra.CompletionWithDesc(key.Name, key.Help))
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// configPath returns the file "set", "unset" and "edit" change: the nearest
+// project file, or a new one in the current directory, with --project, and
+// the user file otherwise.
+func configPath(project bool) (string, error) {
+	if !project {
+		return config.UserPath()
+	}
+	if path := config.ProjectPath(); path != "" {
+		return path, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, config.ProjectFile), nil
+}
+
+func newConfigGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "get <key>",
+		Short:             "Print the effective value of a setting and where it comes from",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeConfigKey,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := config.FindKey(args[0]); err != nil {
+				return err
+			}
+			value, source := config.Lookup(args[0])
+			fmt.Printf("%s\t(%s)\n", value, source)
+			return nil
+		},
+	}
+}
+
+func newConfigSetCmd() *cobra.Command {
+	var project bool
+
+	cmd := &cobra.Command{
+		Use:               "set <key> <value>",
+		Short:             "Store a setting in the user or project config file",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeConfigKey,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[1] == "" {
+				return fmt.Errorf("empty value: use 'mytodo config unset %s'", args[0])
+			}
+			return storeSetting(project, args[0], args[1])
+		},
+	}
+
+	cmd.Flags().BoolVar(&project, "project", false, "Write to the project file (.mytodo.yaml) instead of the user file")
+	return cmd
+}
+
+func newConfigUnsetCmd() *cobra.Command {
+	var project bool
+
+	cmd := &cobra.Command{
+		Use:               "unset <key>",
+		Short:             "Remove a setting from the user or project config file",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeConfigKey,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return storeSetting(project, args[0], "")
+		},
+	}
+
+	cmd.Flags().BoolVar(&project, "project", false, "Remove from the project file (.mytodo.yaml) instead of the user file")
+	return cmd
+}
+
+func storeSetting(project bool, name, value string) error {
+	key, err := config.FindKey(name)
+	if err != nil {
+		return err
+	}
+	if project && value != "" {
+		if err := key.AllowedIn(config.SourceProject); err != nil {
+			return err
+		}
+	}
+	path, err := configPath(project)
+	if err != nil {
+		return err
+	}
+	if err := config.Set(path, name, value); err != nil {
+		return fmt.Errorf("updating %s: %w", path, err)
+	}
+
+	if value == "" {
+		fmt.Printf("✅ Removed %s from %s\n", name, path)
+	} else {
+		fmt.Printf("✅ Set %s in %s\n", name, path)
+	}
+	if key.Env != "" && os.Getenv(key.Env) != "" {
+		fmt.Printf("Note: %s is set in the environment and takes precedence.\n", key.Env)
+	}
+	return nil
+}
+
+// configEntry is a row of "config list".
+type configEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Env    string `json:"env,omitempty"`
+}
+
+func newConfigListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List every setting with its value and source; secrets are masked",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries := make([]configEntry, 0, len(config.Keys))
+			for _, key := range config.Keys {
+				value, source := config.Lookup(key.Name)
+				if key.Secret && value != "" {
+					value = "********"
+				}
+				entries = append(entries, configEntry{Key: key.Name, Value: value, Source: string(source), Env: key.Env})
+			}
+
+			if outputFormat.Structured() {
+				return output.Encode(os.Stdout, outputFormat, entries)
+			}
+			rows := make([][]string, 0, len(entries))
+			for _, entry := range entries {
+				rows = append(rows, []string{entry.Key, entry.Value, entry.Source, entry.Env})
+			}
+			if err := output.WriteTable(os.Stdout, []string{"key", "value", "source", "env"}, rows); err != nil {
+				return err
+			}
+
+			if outputFormat == output.Plain {
+				fmt.Println()
+				if path := config.ProjectPath(); path != "" {
+					fmt.Printf("Project file: %s\n", path)
+				}
+				if path, err := config.UserPath(); err == nil {
+					fmt.Printf("User file:    %s\n", path)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newConfigEditCmd() *cobra.Command {
+	var project bool
+
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Open the user or project config file in $VISUAL or $EDITOR",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := configPath(project)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					return err
+				}
+				if err := os.WriteFile(path, []byte(config.Skeleton()), 0600); err != nil {
+					return err
+				}
+			}
+
+			editor := os.Getenv("VISUAL")
+			if editor == "" {
+				editor = os.Getenv("EDITOR")
+			}
+			if editor == "" {
+				editor = "vi"
+			}
+			// The editor may come with arguments, e.g. "code --wait".
+			edit := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+			edit.Stdin, edit.Stdout, edit.Stderr = os.Stdin, os.Stdout, os.Stderr
+			if err := edit.Run(); err != nil {
+				return fmt.Errorf("running %s: %w", editor, err)
+			}
+
+			source := config.SourceUser
+			if project {
+				source = config.SourceProject
+			}
+			if _, err := config.ReadFile(path, source); err != nil {
+				return fmt.Errorf("%w; edit the file again to fix it", err)
+			}
+			fmt.Printf("✅ Saved %s\n", path)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&project, "project", false, "Edit the project file (.mytodo.yaml) instead of the user file")
+	return cmd
+}
+
+func completeProfile(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return append([]cobra.Completion{config.DefaultProfile}, config.Profiles()...), cobra.ShellCompDirectiveNoFileComp
+}
+
+func newConfigProfilesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "profiles",
+		Short: "List the profiles and the JIRA site each one uses; * marks the active one",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			active := config.ActiveProfile()
+			names := append([]string{""}, config.Profiles()...)
+
+			var rows [][]string
+			for _, name := range names {
+				url, _ := config.LookupIn(name, "jira.url")
+				label, mark := name, ""
+				if name == "" {
+					label = config.DefaultProfile
+				}
+				if name == active {
+					mark = "*"
+				}
+				rows = append(rows, []string{mark, label, url})
+			}
+			return output.WriteTable(os.Stdout, []string{"", "profile", "jira url"}, rows)
+		},
+	}
+}
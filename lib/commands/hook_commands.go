@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+	"mytodo/lib/hooks"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+func NewHooksCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "hooks",
+		Short: "List the installed task event hooks",
+		Long: `Hooks are executables run on task events: on-add, on-done, on-edit,
+on-comment and on-remove. They live in the mytodo/hooks directory under your
+user config directory, named after the event ("on-add"), or with a suffix
+("on-add.slack", "on-add.naming") to install several; those run in name order.
+
+A hook gets the task as JSON on stdin, the event name in MYTODO_EVENT and the
+task file in MYTODO_FILE. It runs before the change is saved: exiting non-zero
+rejects the change, and what the hook printed on stderr is shown as the reason.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := hooks.DefaultDir()
+			if err != nil {
+				return err
+			}
+			runner := hooks.New(dir, GetTaskList().FilePath())
+
+			fmt.Printf("Hooks directory: %s\n\n", dir)
+			for _, event := range hooks.Events {
+				paths, err := runner.Find(event)
+				if err != nil {
+					return err
+				}
+				if len(paths) == 0 {
+					fmt.Printf("%-11s (none)\n", event)
+					continue
+				}
+				for i, path := range paths {
+					label := ""
+					if i == 0 {
+						label = string(event)
+					}
+					fmt.Printf("%-11s %s\n", label, filepath.Base(path))
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -1,12 +1,11 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
 	"mytodo/lib/jira"
-	"mytodo/lib/quip"
-	"mytodo/lib/utils"
+	"mytodo/lib/output"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/gomarkdown/markdown"
@@ -20,54 +19,72 @@ func NewJiraSummaryCmd() *cobra.Command {
 		Use:   "jira-summary",
 		Short: "Show epic / task progress in JIRA",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !utils.AgentEnabled() {
+			if !agentAvailable() {
 				return fmt.Errorf("JIRA/AI not configured (missing env vars)")
 			}
 			// confirmation
-			yes, err := llmAgent.AskConfirmation("Summarise JIRA project? (yes/no)")
+			yes, err := askAgent(cmd, "Summarise JIRA project? (yes/no)")
 			if err != nil || !yes {
 				return err
 			}
 
-			client := jira.NewClient(utils.GetJiraURL(), utils.GetJiraEmail(), utils.GetJiraToken())
-			project := utils.GetProjectKey()
+			client, err := newJiraClient()
+			if err != nil {
+				return err
+			}
+			project := client.Project
 			// 1. list epics
 			epics, err := client.Search(fmt.Sprintf("project=%s AND type=Epic", project))
 			if err != nil {
 				return err
 			}
-			fmt.Printf("Project %s has %d epics\n", project, len(epics))
 
 			// 2. for each epic, list stories & compute stats
+			progress := []jira.EpicProgress{}
 			for _, epic := range epics {
-				epicKey := epic["key"].(string)
-				epicName := epic["fields"].(map[string]interface{})["summary"].(string)
-				fmt.Printf("\nEpic %s: %s\n", epicKey, epicName)
+				p := jira.EpicProgress{
+					Key:     epic["key"].(string),
+					Summary: epic["fields"].(map[string]interface{})["summary"].(string),
+				}
 
-				stories, err := client.Search(fmt.Sprintf("project=%s AND \"Epic Link\"=%s", project, epicKey))
+				stories, err := client.Search(fmt.Sprintf("project=%s AND %s=%s", project, client.Fields.EpicLinkClauses()[0], p.Key))
 				if err != nil {
 					return err
 				}
 
-				var completed, pending, estPoints float64
+				p.Stories = len(stories)
 				for _, s := range stories {
 					status := s["fields"].(map[string]interface{})["status"].(map[string]interface{})["name"].(string)
 					if status == "Done" {
-						completed++
+						p.Done++
 					} else {
-						pending++
+						p.Pending++
 					}
-					// assume story points field = customfield_10016
-					if v, ok := s["fields"].(map[string]interface{})["customfield_10016"]; ok {
-						if num, ok := v.(float64); ok {
-							estPoints += num
-							// For actual points, you might have another custom field
-						}
+					if num, ok := client.Fields.Points(s["fields"].(map[string]interface{})); ok {
+						p.EstimatedPoints += num
+						// For actual points, you might have another custom field
 					}
 				}
-				fmt.Printf("  %d stories: %d done, %d pending\n", len(stories), int(completed), int(pending))
-				fmt.Printf("  Est. points: %.1f\n", estPoints)
 				// … add time estimation logic if you have fields …
+				progress = append(progress, p)
+			}
+
+			switch {
+			case outputFormat.Structured():
+				return output.Encode(os.Stdout, outputFormat, progress)
+			case outputFormat == output.Table:
+				var rows [][]string
+				for _, p := range progress {
+					rows = append(rows, []string{p.Key, p.Summary, strconv.Itoa(p.Stories), strconv.Itoa(p.Done), strconv.Itoa(p.Pending), fmt.Sprintf("%.1f", p.EstimatedPoints)})
+				}
+				return output.WriteTable(os.Stdout, []string{"epic", "summary", "stories", "done", "pending", "points"}, rows)
+			}
+
+			fmt.Printf("Project %s has %d epics\n", project, len(progress))
+			for _, p := range progress {
+				fmt.Printf("\nEpic %s: %s\n", p.Key, p.Summary)
+				fmt.Printf("  %d stories: %d done, %d pending\n", p.Stories, p.Done, p.Pending)
+				fmt.Printf("  Est. points: %.1f\n", p.EstimatedPoints)
 			}
 			return nil
 		},
@@ -79,10 +96,10 @@ func NewJiraCreateCmd() *cobra.Command {
 		Use:   "jira-create",
 		Short: "Create a new JIRA task with an optional label",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !utils.AgentEnabled() {
+			if !agentAvailable() {
 				return fmt.Errorf("JIRA/AI not configured")
 			}
-			yes, err := llmAgent.AskConfirmation("Create JIRA issue? (yes/no)")
+			yes, err := askAgent(cmd, "Create JIRA issue? (yes/no)")
 			if err != nil || !yes {
 				return err
 			}
@@ -95,7 +112,10 @@ func NewJiraCreateCmd() *cobra.Command {
 			fmt.Print("Label (optional): ")
 			fmt.Scanln(&label)
 
-			client := jira.NewClient(utils.GetJiraURL(), utils.GetJiraEmail(), utils.GetJiraToken())
+			client, err := newJiraClient()
+			if err != nil {
+				return err
+			}
 			key, err := client.Create(summary, desc, "Task", []string{label})
 			if err != nil {
 				return err
@@ -107,7 +127,7 @@ func NewJiraCreateCmd() *cobra.Command {
 }
 
 func NewJiraEpicTrackerCmd() *cobra.Command {
-	var outputFormat string
+	var tableFormat string
 	var quipDocURL string
 	var outputFile string
 
@@ -117,24 +137,28 @@ func NewJiraEpicTrackerCmd() *cobra.Command {
 		Long: `Query all stories, tasks, and bugs linked to a JIRA epic and display them in a tracker table format.
 The table includes ticket ID, description, owner, status, estimates, and other metadata.
 
+The table goes to stdout in --format (markdown or csv), and to --file and
+--quip when given. With --output json or yaml, stdout gets the typed rows
+instead, and progress messages go to stderr.
+
 Example: mytodo jira-epic-tracker ASD-146
+Example: mytodo jira-epic-tracker ASD-146 --format csv --file tracker.csv
+Example: mytodo jira-epic-tracker ASD-146 --output json
 Example with Quip: mytodo jira-epic-tracker ASD-146 --quip "https://domain.quip.com/ABCD123/My-test-project"`,
-		Args: cobra.ExactArgs(1),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeEpicKey,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			epicKey := args[0]
 
-			// Check JIRA configuration
-			jiraURL := utils.GetJiraURL()
-			jiraEmail := utils.GetJiraEmail()
-			jiraToken := utils.GetJiraToken()
-			if jiraURL == "" || jiraEmail == "" || jiraToken == "" {
-				return fmt.Errorf("JIRA not configured. Please set JIRA_URL, JIRA_EMAIL, and JIRA_TOKEN environment variables")
+			// Create JIRA client for the active profile
+			client, err := newJiraClient()
+			if err != nil {
+				return err
 			}
 
-			fmt.Printf("Fetching epic %s and linked issues...\n", epicKey)
-
-			// Create JIRA client
-			client := jira.NewClient(jiraURL, jiraEmail, jiraToken)
+			status := statusOut()
+			client.Log = status
+			fmt.Fprintf(status, "Fetching epic %s and linked issues...\n", epicKey)
 
 			// Get the epic details
 			epicIssue, err := client.GetIssue(epicKey)
@@ -152,7 +176,10 @@ Example with Quip: mytodo jira-epic-tracker ASD-146 --quip "https://domain.quip.
 				epicName = summary
 			}
 
-			fmt.Printf("Epic: %s - %s\n", epicKey, epicName)
+			fmt.Fprintf(status, "Epic: %s - %s\n", epicKey, epicName)
+
+			// Remember the epic for shell completion; failing to do so is harmless.
+			_ = jira.RememberEpic(epicKey, epicName)
 
 			// Get all issues linked to the epic (children, subtasks, and linked items)
 			issues, err := client.GetIssuesByEpic(epicKey)
@@ -160,18 +187,9 @@ Example with Quip: mytodo jira-epic-tracker ASD-146 --quip "https://domain.quip.
 				return fmt.Errorf("failed to fetch linked issues: %w", err)
 			}
 
-			fmt.Printf("Found %d related issues (child work items, subtasks, and linked issues)\n\n", len(issues))
-
-			fmt.Printf("Begin Debug\n")
-			for _, issue := range issues {
-				if issueKey, ok := issue["key"].(string); ok && issueKey == "BWC-209" {
-					issueJSON, _ := json.MarshalIndent(issue, "", "  ")
-					fmt.Printf("%s\n", issueJSON)
-				}
-			}
-			fmt.Printf("End Debug\n")
+			fmt.Fprintf(status, "Found %d related issues (child work items, subtasks, and linked issues)\n\n", len(issues))
 
-			if len(issues) == 0 {
+			if len(issues) == 0 && !outputFormat.Structured() {
 				fmt.Println("No issues related to this epic")
 				return nil
 			}
@@ -188,51 +206,66 @@ Example with Quip: mytodo jira-epic-tracker ASD-146 --quip "https://domain.quip.
 				}
 			}
 
-			fmt.Println("Issue breakdown by type:")
+			fmt.Fprintln(status, "Issue breakdown by type:")
 			for typeName, count := range typeCount {
-				fmt.Printf("  - %s: %d\n", typeName, count)
+				fmt.Fprintf(status, "  - %s: %d\n", typeName, count)
 			}
-			fmt.Println()
+			fmt.Fprintln(status)
 
 			// Convert issues to tracker rows
-			var rows []*jira.TicketRow
+			rows := []*jira.TicketRow{}
 			for _, issue := range issues {
-				if row := jira.ExtractTicketRow(issue); row != nil {
+				if row := jira.ExtractTicketRow(issue, client.Fields); row != nil {
 					rows = append(rows, row)
 				}
 			}
+			jira.AssignWeights(rows)
 
 			// Format output based on requested format
-			var output string
-			switch outputFormat {
+			var tableText string
+			switch tableFormat {
 			case "csv":
-				output = jira.FormatAsCSV(rows)
+				tableText = jira.FormatAsCSV(rows)
 			case "markdown", "md":
-				output = jira.FormatAsMarkdownTable(rows, epicKey, epicName)
+				tableText = jira.FormatAsMarkdownTable(rows, epicKey, epicName)
 			default:
-				output = jira.FormatAsMarkdownTable(rows, epicKey, epicName)
+				tableText = jira.FormatAsMarkdownTable(rows, epicKey, epicName)
 			}
 
 			// Display output
-			fmt.Println(output)
+			switch {
+			case outputFormat.Structured():
+				tracker := jira.Tracker{EpicKey: epicKey, EpicName: epicName, Rows: rows}
+				if err := output.Encode(os.Stdout, outputFormat, tracker); err != nil {
+					return err
+				}
+			case outputFormat == output.Table:
+				var table [][]string
+				for _, row := range rows {
+					table = append(table, []string{row.Ticket, row.Status, row.Owner, fmt.Sprintf("%.0f%%", row.PercentCompletion), fmt.Sprintf("%.1f", row.DaysToQAEstimated), row.Description})
+				}
+				if err := output.WriteTable(os.Stdout, []string{"ticket", "status", "owner", "done", "est days", "description"}, table); err != nil {
+					return err
+				}
+			default:
+				fmt.Println(tableText)
+			}
 
 			// Save to file if requested
 			if outputFile != "" {
-				if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
+				if err := os.WriteFile(outputFile, []byte(tableText), 0644); err != nil {
 					return fmt.Errorf("failed to write output file: %w", err)
 				}
-				fmt.Printf("\n✅ Saved to %s\n", outputFile)
+				fmt.Fprintf(status, "\n✅ Saved to %s\n", outputFile)
 			}
 
 			// Append to Quip if URL is provided
 			if quipDocURL != "" {
-				quipToken := utils.GetQuipToken()
-				if quipToken == "" {
-					return fmt.Errorf("Quip not configured. Please set QUIP_TOKEN environment variable")
+				quipClient, err := newQuipClient()
+				if err != nil {
+					return err
 				}
 
-				quipClient := quip.NewClient(quipToken)
-
 				// Extract thread ID from Quip URL
 				// Thread ID is the part after the domain before the slash
 				threadID, err := extractQuipThreadID(quipDocURL)
@@ -240,32 +273,32 @@ Example with Quip: mytodo jira-epic-tracker ASD-146 --quip "https://domain.quip.
 					return fmt.Errorf("invalid Quip URL: %w", err)
 				}
 
-				fmt.Printf("\nAppending to Quip document: %s\n", quipDocURL)
+				fmt.Fprintf(status, "\nAppending to Quip document: %s\n", quipDocURL)
 
 				// Prepare content with separator
 				separator := fmt.Sprintf("\n\n---\n**Updated: %s**\n\n", epicKey)
-				contentToAppend := separator + output
+				contentToAppend := separator + tableText
 
 				err = quipClient.AppendTableToDocumentAfter(threadID, markdownToHTML(contentToAppend), "PROJECT_TRACKING_TABLE")
 				if err != nil {
 					// If HTML fails, try markdown format directly
-					fmt.Printf("HTML append failed, trying markdown format: %v\n", err)
+					fmt.Fprintf(status, "HTML append failed, trying markdown format: %v\n", err)
 					err = quipClient.AppendToDocument(threadID, contentToAppend)
 					if err != nil {
 						return fmt.Errorf("failed to append to Quip document: %w", err)
 					}
 				}
 
-				fmt.Printf("✅ Content appended to Quip document: %s\n", quipDocURL)
+				fmt.Fprintf(status, "✅ Content appended to Quip document: %s\n", quipDocURL)
 			}
 
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVarP(&outputFormat, "format", "f", "markdown", "Output format: markdown, csv")
+	cmd.Flags().StringVarP(&tableFormat, "format", "f", "markdown", "Table format: markdown, csv")
 	cmd.Flags().StringVarP(&quipDocURL, "quip", "q", "", "Quip document URL to append to (e.g., https://domain.quip.com/ABC123/Document-Name)")
-	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Save output to file")
+	cmd.Flags().StringVar(&outputFile, "file", "", "Save the table to a file")
 
 	return cmd
 }
@@ -0,0 +1,289 @@
+package commands
+
+import (
+	"fmt"
+	"mytodo/lib/output"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/utils"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func NewAttachCmd() *cobra.Command {
+	var title string
+
+	cmd := &cobra.Command{
+		Use:   "attach [task number or ID] [path-or-url]",
+		Short: "Link a URL, file, JIRA issue or Quip document to a task",
+		Long: `Attach a link to a task. The kind of link is detected from the target:
+
+  https://domain.quip.com/...   Quip document
+  http(s)://...                 web page
+  ABC-123                       JIRA issue
+  anything else                 local file (stored as an absolute path)
+
+Example: mytodo attach 3 https://github.com/org/repo/pull/42
+Example: mytodo attach 3 ASD-146 --title "Billing epic"
+Example: mytodo attach 3 ./notes/design.md`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeAttach,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			index, err := resolveTaskRef(args[0])
+			if err != nil {
+				return err
+			}
+
+			link := tasklist.ParseLink(args[1])
+			link.Title = title
+			if link.Type == tasklist.LinkFile {
+				if link.Target, err = filepath.Abs(link.Target); err != nil {
+					return err
+				}
+				if _, err := os.Stat(link.Target); err != nil {
+					return fmt.Errorf("cannot attach %s: %w", link.Target, err)
+				}
+			}
+
+			task := GetTaskList().GetTask(index)
+			for _, existing := range task.Links {
+				if existing.Type == link.Type && existing.Target == link.Target {
+					return fmt.Errorf("task %d already links to %s", index, link.Target)
+				}
+			}
+			task.Links = append(task.Links, link)
+			if err := GetTaskList().ReplaceTask(index, task); err != nil {
+				return err
+			}
+			if outputFormat != output.Plain {
+				return finishChange(index, task, nil)
+			}
+
+			fmt.Printf("✅ Attached %s to task %d.\n", link, index)
+			printToStdout()
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&title, "title", "", "Title to show for the link")
+
+	return cmd
+}
+
+func NewDetachCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "detach [task number or ID] [link number]",
+		Short:             "Remove a link from a task",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeLinkNumber(completeTaskNumber(hasLinks)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			index, err := resolveTaskRef(args[0])
+			if err != nil {
+				return err
+			}
+			task := GetTaskList().GetTask(index)
+			n, err := linkNumber(task, args[1])
+			if err != nil {
+				return err
+			}
+
+			removed := task.Links[n]
+			task.Links = append(task.Links[:n], task.Links[n+1:]...)
+			if err := GetTaskList().ReplaceTask(index, task); err != nil {
+				return err
+			}
+			if outputFormat != output.Plain {
+				return finishChange(index, task, nil)
+			}
+			fmt.Printf("✅ Removed %s from task %d.\n", removed, index)
+			return nil
+		},
+	}
+}
+
+func NewOpenCmd() *cobra.Command {
+	var printOnly bool
+	var live bool
+
+	cmd := &cobra.Command{
+		Use:   "open [task number or ID] [link number]",
+		Short: "Open a task's link, or list its links",
+		Long: `Open a link of a task with the system's opener (xdg-open, open or start).
+JIRA keys open under the JIRA URL of the active profile (see --profile). With
+several links, pass the link number or leave it out to list them.
+
+--live fetches the current title and status of JIRA issues and the title of
+Quip documents, with the credentials of the active profile.
+
+Example: mytodo open 3
+Example: mytodo open 3 1 --print
+Example: mytodo open 3 --live`,
+		Args:              cobra.RangeArgs(1, 2),
+		ValidArgsFunction: completeLinkNumber(completeTaskNumber(hasLinks)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			index, err := resolveTaskRef(args[0])
+			if err != nil {
+				return err
+			}
+			task := GetTaskList().GetTask(index)
+			if len(task.Links) == 0 {
+				return fmt.Errorf("task %d has no links; add one with 'mytodo attach'", index)
+			}
+
+			if len(args) == 1 && (len(task.Links) > 1 || live) {
+				fmt.Printf("%d. %s\n", index, task.Content)
+				for i, link := range task.Links {
+					line := fmt.Sprintf("  %d. %s", i, link)
+					if live {
+						line += describeLive(link)
+					}
+					fmt.Println(line)
+				}
+				return nil
+			}
+
+			n := 0
+			if len(args) == 2 {
+				if n, err = linkNumber(task, args[1]); err != nil {
+					return err
+				}
+			}
+			link := task.Links[n]
+
+			target, err := linkLocation(link)
+			if err != nil {
+				return err
+			}
+			if live {
+				fmt.Println(link.String() + describeLive(link))
+			}
+			if printOnly {
+				fmt.Println(target)
+				return nil
+			}
+			if err := openInSystem(target); err != nil {
+				// Headless machines have no opener; the location is still useful.
+				fmt.Println(target)
+				return fmt.Errorf("could not open link: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&printOnly, "print", "p", false, "Print the link location instead of opening it")
+	cmd.Flags().BoolVar(&live, "live", false, "Fetch the current title/status of JIRA and Quip links")
+
+	return cmd
+}
+
+func linkNumber(task *tasklist.Task, arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 || n >= len(task.Links) {
+		return -1, fmt.Errorf("invalid link number %q: task has %d link(s)", arg, len(task.Links))
+	}
+	return n, nil
+}
+
+// linkLocation returns what to hand to the system opener.
+func linkLocation(link tasklist.Link) (string, error) {
+	if link.Type != tasklist.LinkJira {
+		return link.Target, nil
+	}
+	jiraURL := utils.GetJiraURL()
+	if jiraURL == "" {
+		return "", notConfigured("JIRA", "jira.url")
+	}
+	return strings.TrimSuffix(jiraURL, "/") + "/browse/" + link.Target, nil
+}
+
+func openInSystem(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
+
+// describeLive looks up the current state of JIRA and Quip links. Failures
+// are reported inline so one bad link does not hide the others.
+func describeLive(link tasklist.Link) string {
+	switch link.Type {
+	case tasklist.LinkJira:
+		client, err := newJiraClient()
+		if err != nil {
+			return " — JIRA not configured"
+		}
+		issue, err := client.GetIssue(link.Target)
+		if err != nil {
+			return " — " + err.Error()
+		}
+		fields, _ := issue["fields"].(map[string]interface{})
+		summary, _ := fields["summary"].(string)
+		status := ""
+		if s, ok := fields["status"].(map[string]interface{}); ok {
+			status, _ = s["name"].(string)
+		}
+		return fmt.Sprintf(" — %s [%s]", summary, status)
+	case tasklist.LinkQuip:
+		client, err := newQuipClient()
+		if err != nil {
+			return " — Quip not configured"
+		}
+		threadID, err := extractQuipThreadID(link.Target)
+		if err != nil {
+			return " — " + err.Error()
+		}
+		thread := client.GetThread(threadID)
+		if thread == nil || thread.Thread["title"] == "" {
+			return " — title unavailable"
+		}
+		return " — " + thread.Thread["title"]
+	}
+	return ""
+}
+
+func hasLinks(task *tasklist.Task) bool {
+	return len(task.Links) > 0
+}
+
+// completeAttach completes the task, then falls back to file names.
+func completeAttach(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeTaskNumber(nil)(cmd, args, toComplete)
+	}
+	if len(args) == 1 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeLinkNumber completes the task with first, then its link numbers.
+func completeLinkNumber(first cobra.CompletionFunc) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return first(cmd, args, toComplete)
+		}
+		if len(args) > 1 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		index, err := resolveTaskRef(args[0])
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var suggestions []cobra.Completion
+		for i, link := range GetTaskList().GetTask(index).Links {
+			suggestions = append(suggestions, cobra.CompletionWithDesc(strconv.Itoa(i), link.String()))
+		}
+		return suggestions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	}
+}
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"mytodo/lib/mcp"
+	"mytodo/lib/service"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func NewMcpCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "mcp",
+		Short: "Run a Model Context Protocol server on stdio",
+		Long: `Speak the Model Context Protocol over stdin/stdout so AI assistants can read
+and update tasks directly.
+
+Resources:
+  mytodo://tasks        all tasks as JSON
+  mytodo://tasks/{id}   a single task
+
+Tools: add_task, complete_task, comment_task, search_tasks
+
+Register it with your assistant as a stdio server running "mytodo mcp".`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server := mcp.NewServer(service.New(GetTaskList()), Version)
+			return server.Serve(os.Stdin, os.Stdout)
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"mytodo/lib/output"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/utils"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// outputFormat is set from the global --output flag.
+var outputFormat = output.Plain
+
+// numberedTask is a task as scripts see it: with the number other commands
+// take as argument.
+type numberedTask struct {
+	Number int `json:"number"`
+	tasklist.Task
+}
+
+// statusOut is where progress messages go: stdout normally, stderr when stdout
+// carries JSON or YAML.
+func statusOut() io.Writer {
+	if outputFormat.Structured() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// writeTasks writes the numbered tasks in the table, JSON or YAML format.
+func writeTasks(w io.Writer, tasks []numberedTask) error {
+	if outputFormat.Structured() {
+		if tasks == nil {
+			tasks = []numberedTask{}
+		}
+		return output.Encode(w, outputFormat, tasks)
+	}
+
+	now := time.Now()
+	rows := make([][]string, 0, len(tasks))
+	for _, task := range tasks {
+		status := "pending"
+		switch {
+		case task.Done:
+			status = "done"
+		case task.IsSnoozed(now):
+			status = "snoozed"
+		}
+		content := task.Content
+		if task.ParentID != "" {
+			content = "↳ " + content
+		}
+		due := ""
+		if task.Due != nil {
+			due = utils.FormatDate(*task.Due)
+		}
+		rows = append(rows, []string{strconv.Itoa(task.Number), status, content, due, string(task.Priority), strings.Join(task.Tags, ",")})
+	}
+	return output.WriteTable(w, []string{"#", "status", "content", "due", "pri", "tags"}, rows)
+}
+
+// emptyList prints msg and reports true when there are no tasks to act on.
+// Other output formats go on to fail on the task number, so that scripts see
+// an error.
+func emptyList(msg string) bool {
+	if outputFormat != output.Plain || GetTaskList().NumberOfTasks() > 0 {
+		return false
+	}
+	fmt.Println(msg)
+	return true
+}
+
+// finishChange ends a command that changed a single task. Plain output shows
+// the error, if any, and the list, as these commands always have. The other
+// formats write just the task, and return the error so it goes to stderr with
+// a non-zero exit status.
+func finishChange(index int, task *tasklist.Task, err error) error {
+	if outputFormat == output.Plain {
+		if err != nil {
+			fmt.Println("Error:", err)
+		}
+		printToStdout()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if outputFormat.Structured() {
+		return output.Encode(os.Stdout, outputFormat, numberedTask{Number: index, Task: *task})
+	}
+	return writeTasks(os.Stdout, []numberedTask{{Number: index, Task: *task}})
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"mytodo/lib/taskjson"
+	"mytodo/lib/utils"
+	"mytodo/lib/vault"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// PluginPrefix is prepended to an unknown subcommand to find its plugin.
+const PluginPrefix = "mytodo-"
+
+// Environment passed to plugins, on top of the caller's.
+const (
+	PluginBinEnvVar       = "MYTODO_BIN"
+	PluginConfigDirEnvVar = "MYTODO_CONFIG_DIR"
+	PluginProtocolEnvVar  = "MYTODO_PROTOCOL_VERSION"
+)
+
+// RunPlugin runs mytodo-<name> from PATH when args start with a subcommand
+// root does not know, the way git and kubectl do. It reports whether a plugin
+// ran and the exit code to finish with.
+func RunPlugin(root *cobra.Command, args []string) (bool, int) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return false, 0
+	}
+	// help and completion are only added on Execute; add them now so a plugin
+	// cannot shadow them.
+	root.InitDefaultHelpCmd()
+	root.InitDefaultCompletionCmd()
+	if cmd, _, err := root.Find(args); err == nil && cmd != root {
+		return false, 0
+	}
+
+	path, err := exec.LookPath(PluginPrefix + args[0])
+	if err != nil {
+		return false, 0
+	}
+
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), pluginEnv()...)
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return true, exitErr.ExitCode()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: running plugin:", err)
+		return true, 1
+	}
+	return true, 0
+}
+
+// pluginEnv tells a plugin where everything is. Plugins read and write tasks
+// through "$MYTODO_BIN export/import --format json" rather than the file, so
+// encryption and hooks keep working; the session key spares them a second
+// passphrase prompt.
+func pluginEnv() []string {
+	env := []string{
+		PluginProtocolEnvVar + "=" + strconv.Itoa(taskjson.Version),
+		utils.TaskFileEnvVar + "=" + GetTaskList().FilePath(),
+	}
+	if bin, err := os.Executable(); err == nil {
+		env = append(env, PluginBinEnvVar+"="+bin)
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		env = append(env, PluginConfigDirEnvVar+"="+filepath.Join(dir, "mytodo"))
+	}
+	if taskVault != nil {
+		env = append(env, vault.SessionEnvVar+"="+taskVault.SessionKey())
+	}
+	return env
+}
+
+// findPlugins returns plugin names and paths found on PATH; earlier PATH
+// entries win, as they would when running the plugin.
+func findPlugins() (names []string, paths map[string]string) {
+	paths = map[string]string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+			if !ok || name == "" || paths[name] != "" {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+			paths[name] = filepath.Join(dir, entry.Name())
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, paths
+}
+
+func NewPluginsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "plugins",
+		Short: "List mytodo-<name> plugins found on PATH",
+		Long: `Any executable called mytodo-<name> on PATH can be run as "mytodo <name>".
+Built-in commands always take precedence.
+
+Plugins get these environment variables:
+  MYTODO_FILE              the task file (the list mytodo is working on)
+  MYTODO_CONFIG_DIR        mytodo's configuration directory
+  MYTODO_BIN               the mytodo executable that started the plugin
+  MYTODO_PROTOCOL_VERSION  version of the JSON task format
+
+To read and write tasks, use the versioned JSON format rather than the file:
+  "$MYTODO_BIN" export --format json
+  "$MYTODO_BIN" import --format json < tasks.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, paths := findPlugins()
+			if len(names) == 0 {
+				fmt.Println("No plugins found on PATH.")
+				return nil
+			}
+			for _, name := range names {
+				note := ""
+				if found, _, err := cmd.Root().Find([]string{name}); err == nil && found != cmd.Root() {
+					note = " (shadowed by the built-in command)"
+				}
+				fmt.Printf("%-16s %s%s\n", name, paths[name], note)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"fmt"
+	"mytodo/lib/config"
+	"mytodo/lib/jira"
+	"mytodo/lib/quip"
+	"mytodo/lib/utils"
+	"strings"
+)
+
+// settingNames spells out settings for an error message, under the active
+// profile when there is one.
+func settingNames(names ...string) string {
+	profile := config.ActiveProfile()
+	for i, name := range names {
+		if profile != "" {
+			names[i] = "profiles." + profile + "." + name
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// notConfigured describes what is missing to use a service.
+func notConfigured(service string, names ...string) error {
+	where := ""
+	if profile := config.ActiveProfile(); profile != "" {
+		where = fmt.Sprintf(" for profile %s", profile)
+	}
+	missing := settingNames(names...)
+	if strings.HasSuffix(names[len(names)-1], "token") {
+		// Tokens can also come from a helper or the credentials file.
+		missing += fmt.Sprintf(" or its _command helper, or run 'mytodo auth login %s'", strings.ToLower(service))
+	}
+	return fmt.Errorf("%s not configured%s: set %s (see 'mytodo config')", service, where, missing)
+}
+
+// newJiraClient returns a client for the JIRA site of the active profile.
+func newJiraClient() (*jira.Client, error) {
+	jiraURL, jiraEmail := utils.GetJiraURL(), utils.GetJiraEmail()
+	if jiraURL == "" || jiraEmail == "" {
+		return nil, notConfigured("JIRA", "jira.url", "jira.email")
+	}
+	jiraToken, err := utils.GetJiraToken()
+	if err != nil {
+		return nil, err
+	}
+	if jiraToken == "" {
+		return nil, notConfigured("JIRA", "jira.token")
+	}
+	client := jira.NewClient(jiraURL, jiraEmail, jiraToken)
+	client.Project = utils.GetProjectKey()
+	client.Fields = jira.FieldMap{
+		StoryPoints: config.Get("jira.fields.story_points"),
+		EpicLink:    config.Get("jira.fields.epic_link"),
+		QADate:      config.Get("jira.fields.qa_date"),
+	}
+	return client, nil
+}
+
+// newQuipClient returns a client for the Quip account of the active profile.
+func newQuipClient() (*quip.Client, error) {
+	token, err := utils.GetQuipToken()
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, notConfigured("Quip", "quip.token")
+	}
+	return quip.NewClient(token), nil
+}
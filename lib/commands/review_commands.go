@@ -0,0 +1,405 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mytodo/lib/review"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/utils"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Review decisions, in the order the summary lists them.
+const (
+	decisionDeferred    = "Deferred"
+	decisionDropped     = "Dropped"
+	decisionPrioritized = "Re-prioritized"
+	decisionBrokenDown  = "Broken down"
+	decisionArchived    = "Archived"
+	decisionKept        = "Kept"
+)
+
+var decisionOrder = []string{
+	decisionDeferred,
+	decisionDropped,
+	decisionPrioritized,
+	decisionBrokenDown,
+	decisionArchived,
+	decisionKept,
+}
+
+func NewReviewCmd() *cobra.Command {
+	var staleDays int
+
+	cmd := &cobra.Command{
+		Use:   "review",
+		Short: "Walk through a guided weekly review of the task list",
+		Long: `Go through the list step by step and decide what to do with each task:
+
+  1. stale tasks, not touched for --stale-days days
+  2. overdue tasks
+  3. tasks with neither tags nor a due date
+  4. completed tasks, to archive
+
+For pending tasks:
+  k  keep as is               d  defer (snooze; overdue tasks also get the new due date)
+  x  drop (remove)            p  re-prioritize
+  b  break down into subtasks q  stop the review
+
+Completed tasks can be archived one by one (a) or all at once (A). Archived
+tasks move to a file next to the task file, e.g. ~/.mytodo.archive.json.
+
+A summary of the decisions is printed at the end.
+
+Example: mytodo review --stale-days 30`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if staleDays < 1 {
+				return fmt.Errorf("--stale-days must be at least 1")
+			}
+			steps := review.Plan(GetTaskList().GetAllTasks(), time.Now(), staleDays)
+			if len(steps) == 0 {
+				fmt.Println("✅ Nothing to review, the list is in good shape.")
+				return nil
+			}
+
+			r := &reviewer{in: bufio.NewReader(os.Stdin), decisions: map[string][]string{}}
+			err := r.run(steps)
+			r.printSummary()
+			return err
+		},
+	}
+
+	cmd.Flags().IntVar(&staleDays, "stale-days", 14, "Days without changes after which a task is stale")
+
+	return cmd
+}
+
+type reviewer struct {
+	in        *bufio.Reader
+	decisions map[string][]string // decision -> descriptions
+	stopped   bool
+}
+
+// ask prompts for a line of input. It reports false once input runs out, which
+// ends the review like "q".
+func (r *reviewer) ask(prompt string) (string, bool) {
+	fmt.Print(prompt)
+	line, err := r.in.ReadString('\n')
+	if err == io.EOF && line == "" {
+		fmt.Println()
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
+
+func (r *reviewer) record(decision, description string) {
+	r.decisions[decision] = append(r.decisions[decision], description)
+}
+
+func (r *reviewer) run(steps []review.Step) error {
+	header := color.New(color.FgCyan, color.Bold).Sprint
+
+	for i, step := range steps {
+		fmt.Printf("\n%s (%d)\n", header(fmt.Sprintf("Step %d/%d: %s", i+1, len(steps), step.Title)), len(step.IDs))
+
+		var err error
+		if step.Kind == review.Completed {
+			err = r.reviewCompleted(step)
+		} else {
+			err = r.reviewPending(step)
+		}
+		if err != nil || r.stopped {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *reviewer) reviewPending(step review.Step) error {
+	for n, id := range step.IDs {
+		index := GetTaskList().FindTask(id)
+		if index == -1 {
+			continue
+		}
+		task := GetTaskList().GetTask(index)
+		fmt.Printf("\n[%d/%d] %d. %s\n", n+1, len(step.IDs), index, task.Content)
+		if details := reviewDetails(task); details != "" {
+			fmt.Printf("      %s\n", details)
+		}
+
+		for done := false; !done; {
+			answer, ok := r.ask("[k]eep [d]efer [x] drop [p]riority [b]reak down [q]uit: ")
+			if !ok || answer == "q" {
+				r.stopped = true
+				return nil
+			}
+
+			var err error
+			switch answer {
+			case "k", "":
+				r.record(decisionKept, task.Content)
+				done = true
+			case "d":
+				done, err = r.deferTask(index, task, step.Kind == review.Overdue)
+			case "x":
+				done, err = r.drop(task)
+			case "p":
+				done, err = r.prioritize(index, task)
+			case "b":
+				done, err = r.breakDown(task)
+			default:
+				fmt.Println("Please answer k, d, x, p, b or q.")
+			}
+			if err != nil {
+				fmt.Println("Error:", err)
+			}
+			if r.stopped {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (r *reviewer) deferTask(index int, task *tasklist.Task, moveDue bool) (bool, error) {
+	answer, ok := r.ask("Defer until [1w]: ")
+	if !ok {
+		r.stopped = true
+		return false, nil
+	}
+	if answer == "" {
+		answer = "1w"
+	}
+	until, err := utils.ParseWhen(answer, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	task.Snoozed = &until
+	if moveDue {
+		task.Due = &until
+	}
+	if err := GetTaskList().ReplaceTask(index, task); err != nil {
+		return false, err
+	}
+	r.record(decisionDeferred, fmt.Sprintf("%s — until %s", task.Content, until.Format("2006-01-02")))
+	return true, nil
+}
+
+func (r *reviewer) prioritize(index int, task *tasklist.Task) (bool, error) {
+	answer, ok := r.ask("Priority (H, M, L, or - for none): ")
+	if !ok {
+		r.stopped = true
+		return false, nil
+	}
+	priority := tasklist.Priority(strings.ToUpper(answer))
+	switch priority {
+	case tasklist.PriorityHigh, tasklist.PriorityMedium, tasklist.PriorityLow:
+	case "-":
+		priority = tasklist.PriorityNone
+	default:
+		return false, fmt.Errorf("unknown priority %q", answer)
+	}
+
+	old := task.Priority
+	task.Priority = priority
+	if err := GetTaskList().ReplaceTask(index, task); err != nil {
+		return false, err
+	}
+	r.record(decisionPrioritized, fmt.Sprintf("%s — %s → %s", task.Content, priorityLabel(old), priorityLabel(priority)))
+	return true, nil
+}
+
+// drop removes task. Its subtasks go with it when confirmed, and otherwise
+// stay as top-level tasks.
+func (r *reviewer) drop(task *tasklist.Task) (bool, error) {
+	subtasks := GetTaskList().Subtasks(task.ID)
+	withSubtasks := false
+	if len(subtasks) > 0 {
+		answer, ok := r.ask(fmt.Sprintf("Drop its %d subtask(s) too? [y/N]: ", len(subtasks)))
+		if !ok {
+			r.stopped = true
+			return false, nil
+		}
+		withSubtasks = strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+	}
+
+	list := GetTaskList()
+	if withSubtasks {
+		// Subtasks first, so that a failure never leaves one without its parent.
+		for i := len(subtasks) - 1; i >= 0; i-- {
+			if index := list.FindTask(subtasks[i]); index != -1 {
+				if err := list.RemoveTask(index); err != nil {
+					return false, err
+				}
+			}
+		}
+	} else {
+		for _, id := range subtasks {
+			index := list.FindTask(id)
+			if index == -1 || list.GetTask(index).ParentID != task.ID {
+				continue
+			}
+			subtask := list.GetTask(index)
+			subtask.ParentID = ""
+			if err := list.ReplaceTask(index, subtask); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if err := list.RemoveTask(list.FindTask(task.ID)); err != nil {
+		return false, err
+	}
+	if withSubtasks {
+		r.record(decisionDropped, fmt.Sprintf("%s — with %d subtask(s)", task.Content, len(subtasks)))
+	} else {
+		r.record(decisionDropped, task.Content)
+	}
+	return true, nil
+}
+
+// breakDown adds subtasks under task. The task itself stays, as the parent.
+func (r *reviewer) breakDown(task *tasklist.Task) (bool, error) {
+	fmt.Println("Subtasks, one per line; an empty line finishes:")
+	added := 0
+	for {
+		line, ok := r.ask("  - ")
+		if !ok {
+			r.stopped = true
+			break
+		}
+		if line == "" {
+			break
+		}
+		subtask := tasklist.Task{
+			Content:  line,
+			ParentID: task.ID,
+			Tags:     append([]string{}, task.Tags...),
+		}
+		if err := GetTaskList().AddTask(&subtask); err != nil {
+			return false, err
+		}
+		added++
+	}
+	if added == 0 {
+		return r.stopped, nil
+	}
+	r.record(decisionBrokenDown, fmt.Sprintf("%s — %d subtask(s)", task.Content, added))
+	return true, nil
+}
+
+func (r *reviewer) reviewCompleted(step review.Step) error {
+	var archive []string
+	all := false
+	for n, id := range step.IDs {
+		index := GetTaskList().FindTask(id)
+		if index == -1 {
+			continue
+		}
+		task := GetTaskList().GetTask(index)
+		if all {
+			archive = append(archive, id)
+			continue
+		}
+		fmt.Printf("\n[%d/%d] %d. %s\n", n+1, len(step.IDs), index, task.Content)
+
+		for answered := false; !answered; {
+			answer, ok := r.ask("[a]rchive [A]rchive all remaining [k]eep [q]uit: ")
+			if !ok || answer == "q" {
+				r.stopped = true
+				return r.archive(archive)
+			}
+			switch answer {
+			case "a":
+				archive = append(archive, id)
+				answered = true
+			case "A":
+				archive = append(archive, id)
+				all = true
+				answered = true
+			case "k", "":
+				r.record(decisionKept, task.Content)
+				answered = true
+			default:
+				fmt.Println("Please answer a, A, k or q.")
+			}
+		}
+	}
+	return r.archive(archive)
+}
+
+func (r *reviewer) archive(ids []string) error {
+	var indexes []int
+	var contents []string
+	for _, id := range ids {
+		if index := GetTaskList().FindTask(id); index != -1 {
+			indexes = append(indexes, index)
+			contents = append(contents, GetTaskList().GetTask(index).Content)
+		}
+	}
+	if err := GetTaskList().Archive(indexes); err != nil {
+		return fmt.Errorf("archiving tasks: %w", err)
+	}
+	for _, content := range contents {
+		r.record(decisionArchived, content)
+	}
+	return nil
+}
+
+func (r *reviewer) printSummary() {
+	header := color.New(color.FgCyan, color.Bold).Sprint
+
+	fmt.Printf("\n%s\n", header("Review summary"))
+	if len(r.decisions) == 0 {
+		fmt.Println("- No decisions made.")
+		return
+	}
+	for _, decision := range decisionOrder {
+		items := r.decisions[decision]
+		if len(items) == 0 {
+			continue
+		}
+		fmt.Printf("%s (%d)\n", decision, len(items))
+		for _, item := range items {
+			fmt.Printf("  - %s\n", item)
+		}
+	}
+	if len(r.decisions[decisionArchived]) > 0 {
+		fmt.Printf("Archived tasks are in %s\n", GetTaskList().ArchivePath())
+	}
+}
+
+// reviewDetails summarises what makes a task worth a look.
+func reviewDetails(task *tasklist.Task) string {
+	var parts []string
+	if touched := task.LastTouched(); touched != nil {
+		parts = append(parts, fmt.Sprintf("last changed %s", touched.Format("2006-01-02")))
+	} else {
+		parts = append(parts, "no change recorded")
+	}
+	if task.Due != nil {
+		parts = append(parts, "due "+utils.FormatDate(*task.Due))
+	}
+	if task.Priority != tasklist.PriorityNone {
+		parts = append(parts, "priority "+string(task.Priority))
+	}
+	for _, tag := range task.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func priorityLabel(p tasklist.Priority) string {
+	if p == tasklist.PriorityNone {
+		return "none"
+	}
+	return string(p)
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mytodo/lib/server"
+	"mytodo/lib/service"
+	"mytodo/lib/utils"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func NewServeCmd() *cobra.Command {
+	var addr string
+	var token string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the task list over a JSON HTTP API",
+		Long: `Expose the task list over JSON HTTP so editor plugins and dashboards can use it.
+
+Endpoints:
+  GET    /tasks                  list tasks (?done=true|false&tag=&q=)
+  POST   /tasks                  create a task
+  GET    /tasks/{id}             get a task
+  PATCH  /tasks/{id}             update fields of a task
+  DELETE /tasks/{id}             delete a task
+  POST   /tasks/{id}/complete    mark a task as done
+  POST   /tasks/{id}/comments    add a comment: {"comment": "..."}
+
+Requests must send "Authorization: Bearer <token>". Send the ETag of a task
+in If-Match to make an update fail with 412 if someone else changed it first.
+
+Example: MYTODO_API_TOKEN=secret mytodo serve --addr :8080`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if token == "" {
+				token = utils.GetAPIToken()
+			}
+			if token == "" {
+				return fmt.Errorf("no API token: pass --token or set %s", utils.APITokenEnvVar)
+			}
+
+			api := server.New(service.New(GetTaskList()), token)
+			httpServer := &http.Server{
+				Addr:              addr,
+				Handler:           api.Handler(),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- httpServer.ListenAndServe()
+			}()
+			fmt.Printf("Serving tasks from %s on %s\n", GetTaskList().FilePath(), addr)
+
+			select {
+			case err := <-errCh:
+				if !errors.Is(err, http.ErrServerClosed) {
+					return err
+				}
+			case <-ctx.Done():
+				fmt.Println("\nShutting down...")
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				return httpServer.Shutdown(shutdownCtx)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", ":8080", "Address to listen on")
+	cmd.Flags().StringVar(&token, "token", "", "Bearer token clients must send (default $"+utils.APITokenEnvVar+")")
+
+	return cmd
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"fmt"
+	"mytodo/lib/standup"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func NewStandupCmd() *cobra.Command {
+	var format string
+	var withJira bool
+
+	cmd := &cobra.Command{
+		Use:   "standup",
+		Short: "Generate a Yesterday / Today / Blockers standup update",
+		Long: `Build a daily standup from the task list:
+
+  Yesterday  tasks completed or commented on since the last working day
+             (on Mondays that is Friday)
+  Today      pending high-priority tasks and tasks due today or overdue
+  Blockers   pending tasks that depend on unfinished tasks
+
+Formats:
+  plain     numbered text for the terminal (default)
+  markdown  Markdown, ready to paste into chat
+  llm       Markdown rewritten into a short update by the AI backend
+
+--jira adds your JIRA issues whose status changed since the last working day,
+from the JIRA site of the active profile (see --profile).
+
+Example: mytodo standup --format markdown --jira`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			archive, err := GetTaskList().LoadArchive()
+			if err != nil {
+				return err
+			}
+			report := standup.Build(GetTaskList().GetAllTasks(), archive.Tasks, time.Now())
+
+			if withJira {
+				issues, err := recentJiraIssues(report.Since)
+				if err != nil {
+					return err
+				}
+				report.Jira = issues
+			}
+
+			switch format {
+			case "plain":
+				fmt.Print(report.Plain())
+			case "markdown", "md":
+				fmt.Println(report.Markdown())
+			case "llm":
+				if !agentAvailable() {
+					return fmt.Errorf("--format llm needs an AI backend (see 'mytodo config')")
+				}
+				if _, err := streamReply(cmd, os.Stdout, fmt.Sprintf(`Rewrite these notes into a short, friendly daily standup update in Markdown
+with the sections Yesterday, Today and Blockers. Keep every fact, invent nothing,
+and answer with the update only.
+
+%s`, report.Markdown())); err != nil {
+					return agentError("LLM prompt error", err)
+				}
+			default:
+				return fmt.Errorf("unknown format %q: use plain, markdown or llm", format)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "plain", "Output format: plain, markdown, llm")
+	cmd.Flags().BoolVar(&withJira, "jira", false, "Include JIRA issues assigned to you that moved recently")
+	cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]cobra.Completion{"plain", "markdown", "llm"}, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+// recentJiraIssues returns the issues assigned to the current user whose
+// status changed since the given time.
+func recentJiraIssues(since time.Time) ([]standup.Issue, error) {
+	client, err := newJiraClient()
+	if err != nil {
+		return nil, err
+	}
+
+	jql := fmt.Sprintf(`assignee = currentUser() AND status CHANGED AFTER "%s" ORDER BY updated DESC`, since.Format("2006-01-02 15:04"))
+	results, err := client.Search(jql)
+	if err != nil {
+		return nil, fmt.Errorf("fetching JIRA issues: %w", err)
+	}
+
+	var issues []standup.Issue
+	for _, result := range results {
+		issue := standup.Issue{}
+		issue.Key, _ = result["key"].(string)
+		fields, _ := result["fields"].(map[string]interface{})
+		issue.Summary, _ = fields["summary"].(string)
+		if s, ok := fields["status"].(map[string]interface{}); ok {
+			issue.Status, _ = s["name"].(string)
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
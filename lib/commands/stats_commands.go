@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"fmt"
+	"mytodo/lib/stats"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+const maxBarWidth = 20
+
+func NewStatsCmd() *cobra.Command {
+	var days int
+	var tagBreakdown bool
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show created vs completed tasks, completion time and a burndown",
+		Long: `Show local productivity statistics over a window of days ending today:
+tasks created and completed per day and per week, the mean time from creation
+to completion, the current open count and a burndown of open tasks.
+
+Tasks added before mytodo recorded timestamps count as created before the
+window and, if done, as completed before it.
+
+Example: mytodo stats --days 30 --by-tag`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if days < 1 {
+				return fmt.Errorf("--days must be at least 1")
+			}
+			archive, err := GetTaskList().LoadArchive()
+			if err != nil {
+				return err
+			}
+			// Archived tasks are done, so they only add to the history.
+			tasks := append(GetTaskList().GetAllTasks(), archive.Tasks...)
+			report := stats.Compute(tasks, days, time.Now())
+			printStats(report, tagBreakdown)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&days, "days", 14, "Number of days to cover, ending today")
+	cmd.Flags().BoolVar(&tagBreakdown, "by-tag", false, "Break the numbers down by tag")
+
+	return cmd
+}
+
+func printStats(report stats.Report, tagBreakdown bool) {
+	header := color.New(color.FgCyan, color.Bold).Sprint
+	created := color.New(color.FgYellow).Sprint
+	completed := color.New(color.FgGreen).Sprint
+
+	fmt.Printf("%s (%s to %s)\n\n", header("Task statistics"), report.From.Format("2006-01-02"), report.To.Format("2006-01-02"))
+
+	scale := 0
+	for _, day := range report.Days {
+		scale = max(scale, day.Created, day.Completed)
+	}
+
+	fmt.Printf("%-10s  %-*s  %s\n", "Day", maxBarWidth+4, "Created", "Completed")
+	for _, day := range report.Days {
+		fmt.Printf("%-10s  %3d %s  %3d %s\n",
+			day.Date.Format("Mon 01-02"),
+			day.Created, created(padRight(bar(day.Created, scale), maxBarWidth)),
+			day.Completed, completed(bar(day.Completed, scale)))
+	}
+
+	fmt.Printf("\n%-10s  %7s  %9s\n", "Week of", "Created", "Completed")
+	for _, week := range report.Weeks {
+		fmt.Printf("%-10s  %7d  %9d\n", week.Start.Format("2006-01-02"), week.Created, week.Completed)
+	}
+
+	open := make([]int, len(report.Days))
+	for i, day := range report.Days {
+		open[i] = day.Open
+	}
+
+	fmt.Printf("\n%s\n", header("Summary"))
+	fmt.Printf("- Open tasks: %d\n", report.Open)
+	fmt.Printf("- Created: %d, completed: %d\n", report.Created, report.Completed)
+	if report.Measured > 0 {
+		fmt.Printf("- Mean time to completion: %s (%d tasks)\n", formatDuration(report.MeanTime), report.Measured)
+	} else {
+		fmt.Println("- Mean time to completion: n/a")
+	}
+	fmt.Printf("- Burndown: %s  (%d → %d open)\n", sparkline(open), open[0], open[len(open)-1])
+	if report.Untracked > 0 {
+		fmt.Printf("- %d task(s) predate timestamps and are only counted as open or done\n", report.Untracked)
+	}
+
+	if !tagBreakdown {
+		return
+	}
+	fmt.Printf("\n%s\n", header("By tag"))
+	if len(report.ByTag) == 0 {
+		fmt.Println("No tagged tasks.")
+		return
+	}
+	fmt.Printf("%-16s  %5s  %7s  %9s  %s\n", "Tag", "Open", "Created", "Completed", "Mean time")
+	for _, tag := range report.ByTag {
+		mean := "n/a"
+		if tag.MeanTime > 0 {
+			mean = formatDuration(tag.MeanTime)
+		}
+		fmt.Printf("%-16s  %5d  %7d  %9d  %s\n", tag.Tag, tag.Open, tag.Created, tag.Completed, mean)
+	}
+}
+
+func bar(n, scale int) string {
+	if n == 0 || scale == 0 {
+		return ""
+	}
+	width := n * maxBarWidth / scale
+	if width == 0 {
+		width = 1
+	}
+	return strings.Repeat("█", width)
+}
+
+func padRight(s string, width int) string {
+	if pad := width - len([]rune(s)); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
+
+func sparkline(values []int) string {
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if hi > lo {
+			i = (v - lo) * (len(sparkTicks) - 1) / (hi - lo)
+		}
+		sb.WriteRune(sparkTicks[i])
+	}
+	return sb.String()
+}
+
+// formatDuration renders a duration in days and hours, or minutes when short.
+func formatDuration(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	if days == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", days, hours)
+}
@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"mytodo/lib/gitsync"
+	"mytodo/lib/tasklist"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSyncCmd() *cobra.Command {
+	var noPush bool
+	var prefer string
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync the task file through git with a task-aware merge",
+		Long: `Commit local changes to the task file and its archive, pull from the upstream
+branch and push.
+
+The task file must live inside a git repository with an upstream branch
+(point MYTODO_FILE at it). When both sides changed the file, tasks are merged
+by ID: fields changed on one side win, comments and tags are combined, and you
+are only asked when the same field was changed on both sides. The archive
+written by "mytodo review" is merged the same way.
+
+Example: MYTODO_FILE=~/todo/mytodo.json mytodo sync`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if prefer != "" && prefer != "local" && prefer != "remote" {
+				return fmt.Errorf("--prefer must be local or remote")
+			}
+
+			repo, err := gitsync.Open(GetTaskList().FilePath(), GetTaskList().ArchivePath())
+			if err != nil {
+				return err
+			}
+
+			host, _ := os.Hostname()
+			committed, err := repo.CommitFile(fmt.Sprintf("mytodo: sync from %s at %s", host, time.Now().Format(time.RFC3339)))
+			if err != nil {
+				return fmt.Errorf("committing local changes: %w", err)
+			}
+			if committed {
+				fmt.Println("Committed local changes.")
+			}
+
+			upstream := repo.Upstream()
+			if upstream == "" {
+				fmt.Println("No upstream branch configured; nothing to pull or push.")
+				return nil
+			}
+
+			if err := repo.Fetch(); err != nil {
+				return fmt.Errorf("fetching %s: %w", upstream, err)
+			}
+
+			switch {
+			case repo.IsAncestor(upstream, "HEAD"):
+				// Nothing new remotely.
+			case repo.IsAncestor("HEAD", upstream):
+				if err := repo.FastForward(upstream); err != nil {
+					return fmt.Errorf("fast-forwarding to %s: %w", upstream, err)
+				}
+				fmt.Printf("Fast-forwarded to %s.\n", upstream)
+			default:
+				if err := mergeUpstream(repo, upstream, prefer); err != nil {
+					return err
+				}
+				fmt.Printf("Merged changes from %s.\n", upstream)
+			}
+
+			if err := GetTaskList().Reload(); err != nil {
+				return fmt.Errorf("reloading tasks: %w", err)
+			}
+
+			if !noPush && !repo.IsAncestor("HEAD", upstream) {
+				if err := repo.Push(); err != nil {
+					return fmt.Errorf("pushing: %w", err)
+				}
+				fmt.Println("Pushed to", upstream)
+			}
+
+			fmt.Println("✅ Tasks are in sync.")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&noPush, "no-push", false, "Commit and merge, but do not push")
+	cmd.Flags().StringVar(&prefer, "prefer", "", "Resolve conflicting fields without prompting: local or remote")
+
+	return cmd
+}
+
+func mergeUpstream(repo *gitsync.Repo, upstream, prefer string) error {
+	base, err := repo.MergeBase("HEAD", upstream)
+	if err != nil {
+		return fmt.Errorf("finding merge base: %w", err)
+	}
+
+	resolve := conflictResolver(prefer)
+	merged, err := mergeVersions(repo.ShowFile, []string{base, "HEAD", upstream}, "task file", resolve)
+	if err != nil {
+		return err
+	}
+	mergedArchive, err := mergeVersions(repo.ShowArchive, []string{base, "HEAD", upstream}, "archive", resolve)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.StartMerge(upstream); err != nil {
+		return fmt.Errorf("merging %s: %w", upstream, err)
+	}
+	if err := GetTaskList().SetTasks(merged); err != nil {
+		repo.AbortMerge()
+		return fmt.Errorf("writing merged tasks: %w", err)
+	}
+	if mergedArchive != nil {
+		archive, err := GetTaskList().LoadArchive()
+		if err == nil {
+			err = archive.SetTasks(mergedArchive)
+		}
+		if err != nil {
+			repo.AbortMerge()
+			return fmt.Errorf("writing merged archive: %w", err)
+		}
+	}
+	if err := repo.FinishMerge(); err != nil {
+		repo.AbortMerge()
+		return fmt.Errorf("committing merge: %w", err)
+	}
+	return nil
+}
+
+// mergeVersions reads a file at the merge base, HEAD and upstream revisions
+// through show and merges its tasks. It returns nil when the file exists at
+// none of them.
+func mergeVersions(show func(rev string) ([]byte, error), revs []string, what string, resolve tasklist.ConflictResolver) ([]tasklist.Task, error) {
+	versions := make([][]tasklist.Task, 0, len(revs))
+	found := false
+	for _, rev := range revs {
+		content, err := show(rev)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s at %s: %w", what, rev, err)
+		}
+		found = found || content != nil
+		tasks, err := GetTaskList().ParseContent(content)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s at %s: %w", what, rev, err)
+		}
+		versions = append(versions, tasks)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	merged, err := tasklist.Merge(versions[0], versions[1], versions[2], resolve)
+	if err != nil {
+		return nil, fmt.Errorf("merging %s: %w", what, err)
+	}
+	return merged, nil
+}
+
+func conflictResolver(prefer string) tasklist.ConflictResolver {
+	reader := bufio.NewReader(os.Stdin)
+
+	return func(c tasklist.Conflict) (bool, error) {
+		switch prefer {
+		case "local":
+			return true, nil
+		case "remote":
+			return false, nil
+		}
+
+		fmt.Printf("\nConflict in task %q, field %q:\n", c.Content, c.Field)
+		fmt.Printf("  local:  %s\n", conflictValue(c.Local))
+		fmt.Printf("  remote: %s\n", conflictValue(c.Remote))
+		for {
+			fmt.Print("Keep (l)ocal or (r)emote? ")
+			answer, err := reader.ReadString('\n')
+			answer = strings.TrimSpace(strings.ToLower(answer))
+			switch answer {
+			case "l", "local":
+				return true, nil
+			case "r", "remote":
+				return false, nil
+			}
+			if err != nil {
+				return false, fmt.Errorf("no answer for conflict in %q: %w", c.Field, err)
+			}
+		}
+	}
+}
+
+func conflictValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
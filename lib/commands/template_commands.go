@@ -0,0 +1,265 @@
+package commands
+
+import (
+	"fmt"
+	"mytodo/lib/output"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/templates"
+	"mytodo/lib/utils"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func NewTemplateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Manage reusable task templates and checklists",
+		Long: `Templates are named sets of tasks, with subtasks, tags and due dates relative
+to the day the template is applied for. They live in the mytodo/templates
+directory under your user config directory, one JSON file per template.
+
+Task text may contain variables written as {{name}}; pass their values with
+--var name=value when applying the template.`,
+	}
+
+	cmd.AddCommand(
+		newTemplateListCmd(),
+		newTemplateShowCmd(),
+		newTemplateApplyCmd(),
+		newTemplateSaveFromCmd(),
+		newTemplateRemoveCmd(),
+	)
+	return cmd
+}
+
+func newTemplateListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the stored templates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := templates.List()
+			if err != nil {
+				return err
+			}
+			if len(names) == 0 {
+				dir, _ := templates.Dir()
+				fmt.Printf("No templates yet. Create one with 'mytodo template save-from' or add a JSON file to %s\n", dir)
+				return nil
+			}
+			for _, name := range names {
+				t, err := templates.Load(name)
+				if err != nil {
+					fmt.Printf("%s\t(invalid: %v)\n", name, err)
+					continue
+				}
+				fmt.Printf("%s\t%s\n", name, t.Description)
+			}
+			return nil
+		},
+	}
+}
+
+func newTemplateShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "show [name]",
+		Short:             "Show the tasks and variables of a template",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTemplateName,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			t, err := templates.Load(args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(t.Name)
+			if t.Description != "" {
+				fmt.Println(t.Description)
+			}
+			if vars := t.Variables(); len(vars) > 0 {
+				fmt.Printf("Variables: %s\n", strings.Join(vars, ", "))
+			}
+			fmt.Println()
+			printTemplateItems(t.Tasks, 0)
+			return nil
+		},
+	}
+}
+
+func printTemplateItems(items []templates.Item, depth int) {
+	for _, item := range items {
+		line := strings.Repeat("  ", depth) + "- " + item.Content
+		if item.Priority != tasklist.PriorityNone {
+			line += fmt.Sprintf(" (%s)", item.Priority)
+		}
+		for _, tag := range item.Tags {
+			line += " #" + tag
+		}
+		if item.Due != "" {
+			line += " [due " + item.Due + "]"
+		}
+		fmt.Println(line)
+		printTemplateItems(item.Subtasks, depth+1)
+	}
+}
+
+func newTemplateApplyCmd() *cobra.Command {
+	var vars []string
+	var due string
+
+	cmd := &cobra.Command{
+		Use:   "apply [name]",
+		Short: "Add the tasks of a template to the task list",
+		Long: `Add the tasks of a template to the task list, substituting {{variables}}.
+
+Due offsets in the template (e.g. -2d) count from --due, which defaults to today
+and accepts the same dates as snooze: friday, 2w, 2025-07-01, ...
+
+Example: mytodo template apply release --var version=1.4 --due friday`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTemplateName,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			t, err := templates.Load(args[0])
+			if err != nil {
+				return err
+			}
+
+			values := map[string]string{}
+			for _, v := range vars {
+				name, value, ok := strings.Cut(v, "=")
+				if !ok || strings.TrimSpace(name) == "" {
+					return fmt.Errorf("invalid --var %q: use name=value", v)
+				}
+				values[strings.TrimSpace(name)] = value
+			}
+
+			anchor, err := utils.ParseWhen(due, time.Now())
+			if err != nil {
+				return err
+			}
+
+			tasks, err := t.Instantiate(values, anchor)
+			if err != nil {
+				return err
+			}
+			var added []numberedTask
+			for i := range tasks {
+				if err := GetTaskList().AddTask(&tasks[i]); err != nil {
+					return fmt.Errorf("adding %q: %w", tasks[i].Content, err)
+				}
+				added = append(added, numberedTask{Number: GetTaskList().NumberOfTasks() - 1, Task: tasks[i]})
+			}
+			if outputFormat != output.Plain {
+				return writeTasks(os.Stdout, added)
+			}
+
+			fmt.Printf("✅ Added %d task(s) from template %s.\n", len(tasks), t.Name)
+			printToStdout()
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Template variable as name=value (repeatable)")
+	cmd.Flags().StringVar(&due, "due", "today", "Date the template's due offsets count from")
+
+	return cmd
+}
+
+func newTemplateSaveFromCmd() *cobra.Command {
+	var name string
+	var description string
+
+	cmd := &cobra.Command{
+		Use:   "save-from [task numbers or IDs...]",
+		Short: "Save existing tasks, with their subtasks, as a template",
+		Long: `Capture tasks from the list as a template. Subtasks of the chosen tasks are
+included. Due dates are stored relative to the latest one, so applying the
+template with --due moves that task to the given date and keeps the spacing.
+
+Edit the saved JSON file to turn text into {{variables}}.
+
+Example: mytodo template save-from 3 4 5 --name release`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeTaskNumbers,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all := GetTaskList().GetAllTasks()
+
+			selected := map[int]bool{}
+			for _, arg := range args {
+				index, err := resolveTaskRef(arg)
+				if err != nil {
+					return err
+				}
+				selected[index] = true
+			}
+			// Pull in subtasks, and theirs, of everything selected.
+			for changed := true; changed; {
+				changed = false
+				for i, task := range all {
+					if selected[i] || task.ParentID == "" {
+						continue
+					}
+					if parent := GetTaskList().FindTask(task.ParentID); parent != -1 && selected[parent] {
+						selected[i] = true
+						changed = true
+					}
+				}
+			}
+
+			var tasks []tasklist.Task
+			for i, task := range all {
+				if selected[i] {
+					tasks = append(tasks, task)
+				}
+			}
+
+			t := templates.FromTasks(name, tasks)
+			t.Description = description
+			if err := templates.Save(t); err != nil {
+				return err
+			}
+			fmt.Printf("✅ Saved %d task(s) as template %s.\n", len(tasks), name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&name, "name", "n", "", "Template name")
+	cmd.Flags().StringVar(&description, "description", "", "One-line description of the template")
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+func newTemplateRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "remove [name]",
+		Short:             "Delete a template",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTemplateName,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := templates.Remove(args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("✅ Removed template %s.\n", args[0])
+			return nil
+		},
+	}
+}
+
+// resolveTaskRef accepts a task number or a task ID and returns its index.
+func resolveTaskRef(ref string) (int, error) {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 0 || n >= GetTaskList().NumberOfTasks() {
+			return -1, fmt.Errorf("invalid task number %d", n)
+		}
+		return n, nil
+	}
+	if index := GetTaskList().FindTask(ref); index != -1 {
+		return index, nil
+	}
+	return -1, fmt.Errorf("no task with number or ID %q", ref)
+}
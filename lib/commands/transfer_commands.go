@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"mytodo/lib/ical"
+	"mytodo/lib/taskjson"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/taskwarrior"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func NewExportCmd() *cobra.Command {
+	var format string
+	var outputFile string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export tasks to another format",
+		Long: `Export all tasks to a file or stdout.
+
+Supported formats:
+  ics   iCalendar VTODO components, one per task. The task ID is used as UID.
+  json  Versioned JSON envelope, the stable format for plugins and scripts:
+        {"format": "mytodo-tasks", "version": 2, "tasks": [...]}
+
+Example: mytodo export --format ics --file tasks.ics`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var writer io.Writer = os.Stdout
+			if outputFile != "" {
+				f, err := os.Create(outputFile)
+				if err != nil {
+					return fmt.Errorf("failed to create output file: %w", err)
+				}
+				defer f.Close()
+				writer = f
+			}
+
+			tasks := GetTaskList().GetAllTasks()
+
+			switch format {
+			case "ics", "ical":
+				if err := ical.Encode(writer, tasks); err != nil {
+					return fmt.Errorf("failed to write iCalendar: %w", err)
+				}
+			case "json":
+				if err := taskjson.Encode(writer, tasks); err != nil {
+					return fmt.Errorf("failed to write JSON: %w", err)
+				}
+			default:
+				return fmt.Errorf("unsupported export format: %s", format)
+			}
+
+			if outputFile != "" {
+				fmt.Printf("✅ Exported %d task(s) to %s\n", len(tasks), outputFile)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "ics", "Export format: ics, json")
+	cmd.Flags().StringVar(&outputFile, "file", "", "Write to this file instead of stdout")
+
+	return cmd
+}
+
+func NewImportCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import tasks from another format",
+		Long: `Import tasks from a file, or from stdin when no file is given.
+
+Supported formats:
+  ics          iCalendar VTODO components. Tasks whose UID matches an
+               existing task ID update that task instead of creating a duplicate.
+  taskwarrior  JSON produced by "task export". The Taskwarrior UUID becomes the
+               task ID; attributes with no equivalent are listed afterwards.
+  json         The envelope written by "export --format json". Tasks with a
+               known ID are updated, the rest are added.
+
+Updating a task only replaces the fields the format carries (for JSON, the
+fields the task gives); links, snoozes and the like that it cannot express are
+kept, and comments keep their timestamps.
+
+Example: mytodo import --format ics tasks.ics
+Example: task export | mytodo import --format taskwarrior`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var reader io.Reader = os.Stdin
+			if len(args) == 1 {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to open input file: %w", err)
+				}
+				defer f.Close()
+				reader = f
+			}
+
+			var tasks []tasklist.Task
+			var fields [][]string // per task; nil for every task with formatFields
+			var formatFields []string
+			var report fmt.Stringer
+			var err error
+
+			switch format {
+			case "ics", "ical":
+				tasks, err = ical.Decode(reader)
+				if err != nil {
+					return fmt.Errorf("failed to parse iCalendar: %w", err)
+				}
+				formatFields = ical.Fields
+			case "json":
+				tasks, fields, err = taskjson.DecodeFields(reader)
+				if err != nil {
+					return fmt.Errorf("failed to parse JSON: %w", err)
+				}
+			case "taskwarrior", "tw":
+				var twReport *taskwarrior.Report
+				tasks, twReport, err = taskwarrior.Decode(reader)
+				if err != nil {
+					return fmt.Errorf("failed to parse Taskwarrior export: %w", err)
+				}
+				report = twReport
+				formatFields = taskwarrior.Fields
+			default:
+				return fmt.Errorf("unsupported import format: %s", format)
+			}
+
+			added, updated := 0, 0
+			for i := range tasks {
+				taskFields := formatFields
+				if fields != nil {
+					taskFields = fields[i]
+				}
+				isNew, err := GetTaskList().UpsertTask(&tasks[i], taskFields)
+				if err != nil {
+					return fmt.Errorf("saving imported tasks: %w", err)
+				}
+				if isNew {
+					added++
+				} else {
+					updated++
+				}
+			}
+
+			fmt.Printf("✅ Imported %d new task(s), updated %d existing task(s).\n", added, updated)
+			if report != nil {
+				fmt.Print(report.String())
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "ics", "Import format: ics, taskwarrior, json")
+
+	return cmd
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"fmt"
+	"mytodo/lib/tui"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func NewUiCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ui",
+		Short: "Browse and edit tasks in a full-screen terminal interface",
+		Long: `Open a keyboard-driven interface over the task list. Changes are saved
+as soon as they are made.
+
+Keys:
+  ↑/↓ j/k        move            space x   toggle done
+  e              edit content    c         add a comment
+  a              add a task      d         delete (asks first)
+  /              filter          v         cycle all/open/done
+  enter tab      detail pane     r         reload from disk
+  q esc          quit`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !term.IsTerminal(int(os.Stdout.Fd())) || !term.IsTerminal(int(os.Stdin.Fd())) {
+				return fmt.Errorf("mytodo ui needs an interactive terminal")
+			}
+			return tui.Run(GetTaskList())
+		},
+	}
+}
@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"fmt"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/vault"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// taskVault is set when the task file is encrypted at rest.
+var taskVault *vault.Vault
+
+// UnlockTaskList installs the decryption codec on t when its file is
+// encrypted. It must run before t.Load.
+func UnlockTaskList(t *tasklist.TaskList) error {
+	content, err := os.ReadFile(t.FilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !vault.IsEncrypted(content) {
+		return nil
+	}
+
+	v, err := vault.Open(content)
+	if err != nil {
+		return fmt.Errorf("unlocking %s: %w", t.FilePath(), err)
+	}
+	taskVault = v
+	t.SetCodec(v)
+	return nil
+}
+
+func NewEncryptCmd() *cobra.Command {
+	var keyFile string
+
+	cmd := &cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt the task file at rest",
+		Long: `Encrypt the task file, and the archive file next to it, with AES-256-GCM
+under a key derived (PBKDF2-SHA256) from a passphrase or a key file.
+
+The passphrase is read from MYTODO_PASSPHRASE or prompted for. With --key-file
+the file's content is used instead; it is generated if it does not exist.
+Later commands find the key through MYTODO_SESSION_KEY (see "mytodo unlock"),
+MYTODO_KEY_FILE, MYTODO_PASSPHRASE or a prompt, in that order.
+
+Example: mytodo encrypt
+Example: mytodo encrypt --key-file ~/.mytodo.key`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if taskVault != nil {
+				return fmt.Errorf("task file is already encrypted")
+			}
+
+			var secret []byte
+			var err error
+			if keyFile != "" {
+				if _, statErr := os.Stat(keyFile); os.IsNotExist(statErr) {
+					if err := vault.GenerateKeyFile(keyFile); err != nil {
+						return err
+					}
+					fmt.Printf("Generated key file %s\n", keyFile)
+				}
+				secret, err = vault.ReadKeyFile(keyFile)
+			} else {
+				secret, err = vault.ResolveSecret(true)
+			}
+			if err != nil {
+				return err
+			}
+
+			v, err := vault.New(secret)
+			if err != nil {
+				return err
+			}
+			if err := recodeArchive(nil, v); err != nil {
+				return err
+			}
+			GetTaskList().SetCodec(v)
+			if err := GetTaskList().Save(); err != nil {
+				return err
+			}
+			taskVault = v
+
+			fmt.Printf("✅ Encrypted %s\n", GetTaskList().FilePath())
+			if keyFile != "" {
+				fmt.Printf("Set %s=%s so mytodo can find the key.\n", vault.KeyFileEnvVar, keyFile)
+			}
+			fmt.Println(`Run 'eval "$(mytodo unlock)"' to avoid entering the passphrase on every command.`)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "Use (or generate) this key file instead of a passphrase")
+
+	return cmd
+}
+
+func NewDecryptCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "decrypt",
+		Short: "Store the task file and its archive as plain JSON again",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if taskVault == nil {
+				return fmt.Errorf("task file is not encrypted")
+			}
+
+			if err := recodeArchive(taskVault, nil); err != nil {
+				return err
+			}
+			GetTaskList().SetCodec(nil)
+			if err := GetTaskList().Save(); err != nil {
+				return err
+			}
+			taskVault = nil
+
+			fmt.Printf("✅ Decrypted %s\n", GetTaskList().FilePath())
+			return nil
+		},
+	}
+}
+
+// recodeArchive rewrites the archive file, stored with codec from, with codec
+// to, so that it stays encrypted like the task file.
+func recodeArchive(from, to tasklist.Codec) error {
+	archive := tasklist.NewTaskList(GetTaskList().ArchivePath())
+	if _, err := os.Stat(archive.FilePath()); os.IsNotExist(err) {
+		return nil
+	}
+	archive.SetCodec(from)
+	if err := archive.Load(); err != nil {
+		return fmt.Errorf("reading archive: %w", err)
+	}
+	archive.SetCodec(to)
+	if err := archive.Save(); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	return nil
+}
+
+func NewUnlockCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unlock",
+		Short: "Print a session key so the shell does not ask for the passphrase again",
+		Long: `Print a shell command that caches the derived key in MYTODO_SESSION_KEY for the
+current shell session. The passphrase itself is never exported.
+
+Example: eval "$(mytodo unlock)"
+Forget it again with: unset MYTODO_SESSION_KEY`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if taskVault == nil {
+				return fmt.Errorf("task file is not encrypted")
+			}
+			fmt.Printf("export %s=%s\n", vault.SessionEnvVar, taskVault.SessionKey())
+			return nil
+		},
+	}
+}
@@ -0,0 +1,689 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProjectFile is looked for in the current directory and its parents.
+	ProjectFile = ".mytodo.yaml"
+	// UserFile lives in the mytodo directory under the user config directory.
+	UserFile = "config.yaml"
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown config key")
+	// ErrUserOnly is returned for a setting found in, or written to, a
+	// project file although only the user file and the environment may set it.
+	ErrUserOnly = errors.New("only the user config file or the environment can set")
+)
+
+// Source tells where a value came from. Sources are listed from the lowest
+// precedence to the highest.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceProject Source = "project"
+	SourceUser    Source = "user"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Key describes a setting.
+type Key struct {
+	Name    string // dotted path in the YAML file, e.g. jira.url
+	Env     string // environment variable that overrides the files, if any
+	Default string
+	Help    string
+	Secret  bool // masked by "config list"
+	Path    bool // relative paths are resolved against the file's directory
+	Profile bool // can be set per profile, under profiles.<name>
+	Command bool // can be set per command, under agent.commands.<command>
+	// UserOnly settings are ignored in project files: a project file comes
+	// with whatever repository is checked out, and must not run commands,
+	// supply secrets or choose the hosts that secrets are sent to.
+	UserOnly bool
+	// ProjectFirst settings are per-directory defaults: a project file's
+	// value beats the user file's.
+	ProjectFirst bool
+	Check        func(value string) error
+}
+
+// AllowedIn reports whether a file of the given source may set the key.
+func (key Key) AllowedIn(source Source) error {
+	if source == SourceProject && key.UserOnly {
+		return fmt.Errorf("%w %s", ErrUserOnly, key.Name)
+	}
+	return nil
+}
+
+// Keys lists every setting mytodo reads.
+var Keys = []Key{
+	{Name: "agent.enabled", Env: "USE_AI", Default: "false", Help: "Turn on AI features", Check: isBool},
+	{Name: "agent.backend", Default: "openai", Help: "AI backend: openai or ollama", Command: true, Check: oneOf("openai", "ollama")},
+	{Name: "agent.model", Help: "Model name; empty for the backend's default", Command: true},
+	{Name: "agent.endpoint", Help: "Base URL of the backend; empty for the backend's default", Command: true, UserOnly: true, Check: isURL},
+	{Name: "agent.temperature", Help: "Sampling temperature, 0 to 2; empty for the backend's default (OpenAI: 0.7)", Command: true, Check: isTemperature},
+	{Name: "agent.max_tokens", Help: "Most tokens to generate; empty for the backend's default (OpenAI: 4096)", Command: true, Check: isPositiveInt},
+	{Name: "agent.timeout", Default: "5m", Help: "How long to wait for a reply, retries included, e.g. 30s or 2m; 0 waits as long as it takes", Command: true, Check: isDuration},
+	{Name: "agent.retries", Default: "3", Help: "How often to retry when the backend is rate limited or failing (HTTP 429 or 5xx); 0 for never", Command: true, Check: isCount},
+	{Name: "agent.api_key", Env: "OPEN_AI_API_KEY", Help: "OpenAI API key", Secret: true, UserOnly: true},
+	{Name: "agent.api_key_command", Help: "Command printing the OpenAI API key, e.g. pass show openai", UserOnly: true},
+
+	{Name: "profile", Env: "MYTODO_PROFILE", Help: "Profile used for JIRA and Quip; empty or default for the settings below", ProjectFirst: true, Check: isProfileName},
+
+	{Name: "jira.url", Env: "JIRA_URL", Help: "JIRA base URL, e.g. https://company.atlassian.net", Profile: true, UserOnly: true, Check: isURL},
+	{Name: "jira.email", Env: "JIRA_EMAIL", Help: "Atlassian account email", Profile: true},
+	{Name: "jira.token", Env: "JIRA_TOKEN", Help: "JIRA API token", Secret: true, Profile: true, UserOnly: true},
+	{Name: "jira.token_command", Help: "Command printing the JIRA API token, e.g. pass show jira", Profile: true, UserOnly: true},
+	{Name: "jira.project", Env: "JIRA_PROJECT_KEY", Help: "Default JIRA project key", Profile: true},
+	{Name: "jira.fields.story_points", Help: "Story points field, e.g. customfield_10016; empty tries the common ones", Profile: true, Check: isCustomField},
+	{Name: "jira.fields.epic_link", Help: "Epic Link field, e.g. customfield_10014; empty tries the common ones", Profile: true, Check: isCustomField},
+	{Name: "jira.fields.qa_date", Help: "Expected QA date field; empty for customfield_10020", Profile: true, Check: isCustomField},
+
+	{Name: "quip.token", Env: "QUIP_TOKEN", Help: "Quip access token", Secret: true, Profile: true, UserOnly: true},
+	{Name: "quip.token_command", Help: "Command printing the Quip access token", Profile: true, UserOnly: true},
+
+	{Name: "storage.file", Env: "MYTODO_FILE", Help: "Task file; defaults to ~/.mytodo.json", Path: true},
+
+	{Name: "display.output", Default: "plain", Help: "Default --output: plain, table, json or yaml", Check: oneOf("plain", "table", "json", "yaml")},
+	{Name: "display.color", Default: "auto", Help: "Color: auto (terminal and no NO_COLOR), always or never", Check: oneOf("auto", "always", "never")},
+	{Name: "display.template", Help: "Default list --template (inline or a format name)"},
+
+	{Name: "server.api_token", Env: "MYTODO_API_TOKEN", Help: "Bearer token required by mytodo serve", Secret: true, UserOnly: true},
+}
+
+type layer struct {
+	source Source
+	path   string
+	values map[string]string
+}
+
+// DefaultProfile selects the top-level settings, like an empty profile.
+const DefaultProfile = "default"
+
+var (
+	files []layer // project, then user
+	flags = map[string]string{}
+
+	profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+)
+
+// FindKey returns the description of a setting. Settings of a profile are
+// named profiles.<profile>.<setting>, e.g. profiles.partner.jira.url, and
+// agent settings of a command agent.commands.<command>.<setting>, e.g.
+// agent.commands.standup.model.
+func FindKey(name string) (Key, error) {
+	if rest, ok := strings.CutPrefix(name, "agent.commands."); ok {
+		command, field, _ := strings.Cut(rest, ".")
+		key, err := FindKey("agent." + field)
+		if err != nil || !key.Command {
+			return Key{}, fmt.Errorf("%w %q (commands hold the agent model and request settings)", ErrUnknownKey, name)
+		}
+		if !profileNamePattern.MatchString(command) {
+			return Key{}, fmt.Errorf("%q: invalid command name %q", name, command)
+		}
+		key.Name, key.Env, key.Default, key.Command = name, "", "", false
+		return key, nil
+	}
+
+	if rest, ok := strings.CutPrefix(name, "profiles."); ok {
+		profile, field, _ := strings.Cut(rest, ".")
+		key, err := FindKey(field)
+		if err != nil || !key.Profile {
+			return Key{}, fmt.Errorf("%w %q (profiles hold the jira.* and quip.* settings)", ErrUnknownKey, name)
+		}
+		if err := isProfileName(profile); err != nil || profile == DefaultProfile {
+			return Key{}, fmt.Errorf("%q: invalid profile name %q", name, profile)
+		}
+		key.Name, key.Env, key.Default, key.Profile = name, "", "", false
+		return key, nil
+	}
+
+	for _, key := range Keys {
+		if key.Name == name {
+			return key, nil
+		}
+	}
+	return Key{}, fmt.Errorf("%w %q (see 'mytodo config list')", ErrUnknownKey, name)
+}
+
+// ActiveProfile returns the name of the selected profile, or "" for the
+// top-level settings.
+func ActiveProfile() string {
+	profile, _ := LookupIn("", "profile")
+	if profile == DefaultProfile {
+		return ""
+	}
+	return profile
+}
+
+// Profiles returns the names of the profiles defined in the config files,
+// sorted.
+func Profiles() []string {
+	seen := map[string]bool{}
+	for _, file := range files {
+		for name := range file.values {
+			if rest, ok := strings.CutPrefix(name, "profiles."); ok {
+				profile, _, _ := strings.Cut(rest, ".")
+				seen[profile] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckProfile reports a selected profile that no config file defines.
+func CheckProfile() error {
+	profile := ActiveProfile()
+	if profile == "" {
+		return nil
+	}
+	for _, name := range Profiles() {
+		if name == profile {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown profile %q (defined: %s)", profile, strings.Join(append([]string{DefaultProfile}, Profiles()...), ", "))
+}
+
+// UserPath returns the location of the user config file.
+func UserPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mytodo", UserFile), nil
+}
+
+// ProjectPath returns the nearest project config file above the current
+// directory, or "" when there is none.
+func ProjectPath() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads the project and user config files. A file with problems is
+// left out entirely, so that a typo cannot half-apply; the problems are
+// returned. A project file that sets a UserOnly key is such a file.
+func Load() error {
+	files = nil
+	var errs []error
+
+	if path := ProjectPath(); path != "" {
+		values, err := ReadFile(path, SourceProject)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			files = append(files, layer{source: SourceProject, path: path, values: values})
+		}
+	}
+
+	if path, err := UserPath(); err == nil {
+		values, err := ReadFile(path, SourceUser)
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		} else if err == nil {
+			files = append(files, layer{source: SourceUser, path: path, values: values})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ReadFile parses and validates a config file of the given source into
+// dotted keys and values.
+func ReadFile(path string, source Source) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	if err := flatten("", tree, values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var problems problemList
+	for _, name := range sortedNames(values) {
+		key, err := FindKey(name)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if err := key.AllowedIn(source); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if key.Check != nil && values[name] != "" {
+			if err := key.Check(values[name]); err != nil {
+				problems = append(problems, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+		}
+		if key.Path && values[name] != "" {
+			values[name] = resolvePath(values[name], filepath.Dir(path))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s: %w", path, problems)
+	}
+	return values, nil
+}
+
+// problemList is every problem of a config file, on one line.
+type problemList []error
+
+func (p problemList) Error() string {
+	messages := make([]string, len(p))
+	for i, err := range p {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (p problemList) Unwrap() []error {
+	return p
+}
+
+func flatten(prefix string, tree map[string]interface{}, values map[string]string) error {
+	for k, v := range tree {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if err := flatten(name, v, values); err != nil {
+				return err
+			}
+		case []interface{}:
+			return fmt.Errorf("%s: lists are not supported", name)
+		case nil:
+			// "jira:" with nothing under it; nothing to set.
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+func resolvePath(value, dir string) string {
+	if rest, ok := strings.CutPrefix(value, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if filepath.IsAbs(value) {
+		return value
+	}
+	return filepath.Join(dir, value)
+}
+
+// SetFlag records a value given on the command line, which beats every
+// other source.
+func SetFlag(name, value string) {
+	flags[name] = value
+}
+
+// Lookup returns the effective value of a setting and where it came from.
+// With a profile selected, the settings a profile holds come from that
+// profile only: neither the top-level settings nor the environment variables
+// of another account leak into it.
+func Lookup(name string) (string, Source) {
+	return LookupIn(ActiveProfile(), name)
+}
+
+// LookupIn is Lookup with the given profile selected; "" selects the
+// top-level settings.
+func LookupIn(profile, name string) (string, Source) {
+	if value, ok := flags[name]; ok {
+		return value, SourceFlag
+	}
+	key, err := FindKey(name)
+	if err != nil {
+		return "", SourceDefault
+	}
+	if key.Profile && profile != "" {
+		for i := len(files) - 1; i >= 0; i-- {
+			if value, ok := files[i].values["profiles."+profile+"."+name]; ok {
+				return value, Source(fmt.Sprintf("%s, profile %s", files[i].source, profile))
+			}
+		}
+		return key.Default, Source(fmt.Sprintf("%s, profile %s", SourceDefault, profile))
+	}
+	if key.Env != "" {
+		if value := os.Getenv(key.Env); value != "" {
+			if key.Path {
+				value = resolvePath(value, ".")
+			}
+			return value, SourceEnv
+		}
+	}
+	for _, file := range searchOrder(key) {
+		if value, ok := file.values[name]; ok {
+			return value, file.source
+		}
+	}
+	return key.Default, SourceDefault
+}
+
+// searchOrder returns the config files in the order they are searched for
+// key: the user file first, unless the key is ProjectFirst.
+func searchOrder(key Key) []layer {
+	if key.ProjectFirst {
+		return files
+	}
+	ordered := make([]layer, 0, len(files))
+	for i := len(files) - 1; i >= 0; i-- {
+		ordered = append(ordered, files[i])
+	}
+	return ordered
+}
+
+// LookupFor is Lookup for an agent setting as a command uses it: a value
+// under agent.commands.<command> beats the general setting and its
+// environment variable; flags beat both.
+func LookupFor(command, name string) (string, Source) {
+	if value, ok := flags[name]; ok {
+		return value, SourceFlag
+	}
+	if key, err := FindKey(name); err == nil && key.Command && command != "" {
+		specific := "agent.commands." + command + "." + strings.TrimPrefix(name, "agent.")
+		for i := len(files) - 1; i >= 0; i-- {
+			if value, ok := files[i].values[specific]; ok {
+				return value, Source(fmt.Sprintf("%s, command %s", files[i].source, command))
+			}
+		}
+	}
+	return Lookup(name)
+}
+
+// Get returns the effective value of a setting.
+func Get(name string) string {
+	value, _ := Lookup(name)
+	return value
+}
+
+// Bool returns a setting as a boolean. Values that are not booleans but are
+// set count as true, as USE_AI=yes always has.
+func Bool(name string) bool {
+	value := Get(name)
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	return value != ""
+}
+
+// Set writes a value into a config file, creating the file if needed and
+// keeping the rest of it, comments included. An empty value removes the key.
+func Set(path, name, value string) error {
+	key, err := FindKey(name)
+	if err != nil {
+		return err
+	}
+	if key.Check != nil && value != "" {
+		if err := key.Check(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: expected a mapping at the top", path)
+	}
+
+	if value == "" {
+		removeNode(root, strings.Split(name, "."))
+	} else {
+		setNode(root, strings.Split(name, "."), value)
+	}
+
+	var out bytes.Buffer
+	if len(root.Content) > 0 || root.HeadComment != "" || root.FootComment != "" {
+		enc := yaml.NewEncoder(&out)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// Config files can hold tokens.
+	return os.WriteFile(path, out.Bytes(), 0600)
+}
+
+func setNode(mapping *yaml.Node, path []string, value string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != path[0] {
+			continue
+		}
+		child := mapping.Content[i+1]
+		if len(path) == 1 {
+			*child = yaml.Node{Kind: yaml.ScalarNode, Value: value, LineComment: child.LineComment}
+			return
+		}
+		if child.Kind != yaml.MappingNode {
+			*child = yaml.Node{Kind: yaml.MappingNode}
+		}
+		setNode(child, path[1:], value)
+		return
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: path[0]}
+	if len(path) == 1 {
+		mapping.Content = append(mapping.Content, keyNode, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+		return
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, keyNode, child)
+	setNode(child, path[1:], value)
+}
+
+func removeNode(mapping *yaml.Node, path []string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+		child := mapping.Content[i+1]
+		if child.Kind == yaml.MappingNode {
+			removeNode(child, path[1:])
+			if len(child.Content) == 0 {
+				mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			}
+		}
+		return
+	}
+}
+
+func sortedNames(values map[string]string) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("%q is not true or false", value)
+	}
+	return nil
+}
+
+func isTemperature(value string) error {
+	t, err := strconv.ParseFloat(value, 64)
+	if err != nil || t < 0 || t > 2 {
+		return fmt.Errorf("%q is not a number from 0 to 2", value)
+	}
+	return nil
+}
+
+func isPositiveInt(value string) error {
+	if n, err := strconv.Atoi(value); err != nil || n < 1 {
+		return fmt.Errorf("%q is not a positive whole number", value)
+	}
+	return nil
+}
+
+func isCount(value string) error {
+	if n, err := strconv.Atoi(value); err != nil || n < 0 {
+		return fmt.Errorf("%q is not a whole number, 0 or more", value)
+	}
+	return nil
+}
+
+func isDuration(value string) error {
+	if value == "0" {
+		return nil
+	}
+	if d, err := time.ParseDuration(value); err != nil || d < 0 {
+		return fmt.Errorf("%q is not a duration like 30s or 2m", value)
+	}
+	return nil
+}
+
+func isProfileName(value string) error {
+	if !profileNamePattern.MatchString(value) {
+		return fmt.Errorf("%q is not a valid profile name (letters, digits, - and _)", value)
+	}
+	return nil
+}
+
+func isCustomField(value string) error {
+	if !strings.HasPrefix(value, "customfield_") {
+		return fmt.Errorf("%q is not a custom field ID like customfield_10016", value)
+	}
+	return nil
+}
+
+func isURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", value)
+	}
+	return nil
+}
+
+func oneOf(allowed ...string) func(string) error {
+	return func(value string) error {
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+// Skeleton is the text of a new config file: every setting, commented out,
+// with its description.
+func Skeleton() string {
+	var b strings.Builder
+	b.WriteString("# mytodo configuration. Environment variables and flags override this file.\n")
+	b.WriteString("# See 'mytodo config list' for the effective values.\n")
+
+	var open []string // mapping keys written for the previous setting
+	for _, key := range Keys {
+		parts := strings.Split(key.Name, ".")
+		parents := parts[:len(parts)-1]
+
+		same := 0
+		for same < len(open) && same < len(parents) && open[same] == parents[same] {
+			same++
+		}
+		if same == 0 {
+			b.WriteString("\n")
+		}
+		for depth := same; depth < len(parents); depth++ {
+			fmt.Fprintf(&b, "#%s%s:\n", strings.Repeat("  ", depth), parents[depth])
+		}
+		open = parents
+
+		indent := strings.Repeat("  ", len(parents))
+		help := key.Help
+		if key.Env != "" {
+			help += " (env " + key.Env + ")"
+		}
+		if indent == "" {
+			fmt.Fprintf(&b, "# %s\n", help)
+		} else {
+			fmt.Fprintf(&b, "#%s# %s\n", indent, help)
+		}
+		fmt.Fprintln(&b, strings.TrimSpace(fmt.Sprintf("#%s%s: %s", indent, parts[len(parts)-1], key.Default)))
+	}
+
+	b.WriteString(`
+# Commands can use their own agent settings, e.g. a small model for list
+# --summary. They go in the agent section above:
+#  commands:
+#    list:
+#      model: gpt-4.1-mini
+#    standup:
+#      temperature: 0.3
+
+# Profiles hold the jira and quip settings of other accounts. Select one with
+# --profile, MYTODO_PROFILE or the profile setting above.
+#profiles:
+#  partner:
+#    jira:
+#      url: https://partner.atlassian.net
+#      email: me@partner.com
+#      token: ...
+#      project: PART
+`)
+	return b.String()
+}
@@ -0,0 +1,323 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setup writes the project and user files ("" for none), points the lookups
+// at them and clears the environment variables of every setting.
+func setup(t *testing.T, project, user string, env map[string]string) (projectDir string, err error) {
+	t.Helper()
+	root := t.TempDir()
+	projectDir = filepath.Join(root, "repo")
+	configDir := filepath.Join(root, "config")
+	for _, dir := range []string{filepath.Join(projectDir, "sub"), filepath.Join(configDir, "mytodo")} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if project != "" {
+		writeFile(t, filepath.Join(projectDir, ProjectFile), project)
+	}
+	if user != "" {
+		writeFile(t, filepath.Join(configDir, "mytodo", UserFile), user)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	for _, key := range Keys {
+		if key.Env != "" {
+			t.Setenv(key.Env, env[key.Env])
+		}
+	}
+	t.Chdir(filepath.Join(projectDir, "sub"))
+	flags = map[string]string{}
+	t.Cleanup(func() { files, flags = nil, map[string]string{} })
+
+	return projectDir, Load()
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLookupPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		project    string
+		user       string
+		env        map[string]string
+		flags      map[string]string
+		command    string // LookupFor this command when set
+		key        string
+		wantValue  string
+		wantSource Source
+	}{
+		{
+			name:       "default",
+			key:        "agent.backend",
+			wantValue:  "openai",
+			wantSource: SourceDefault,
+		},
+		{
+			name:       "project file",
+			project:    "jira:\n  project: PROJ\n",
+			key:        "jira.project",
+			wantValue:  "PROJ",
+			wantSource: SourceProject,
+		},
+		{
+			name:       "user file beats project file",
+			project:    "jira:\n  project: PROJ\n",
+			user:       "jira:\n  project: USER\n",
+			key:        "jira.project",
+			wantValue:  "USER",
+			wantSource: SourceUser,
+		},
+		{
+			name:       "environment beats files",
+			user:       "jira:\n  project: USER\n",
+			env:        map[string]string{"JIRA_PROJECT_KEY": "ENV"},
+			key:        "jira.project",
+			wantValue:  "ENV",
+			wantSource: SourceEnv,
+		},
+		{
+			name:       "flag beats environment",
+			env:        map[string]string{"USE_AI": "false"},
+			flags:      map[string]string{"agent.enabled": "true"},
+			key:        "agent.enabled",
+			wantValue:  "true",
+			wantSource: SourceFlag,
+		},
+		{
+			name:       "project profile beats user profile",
+			project:    "profile: partner\n",
+			user:       "profile: other\n",
+			key:        "profile",
+			wantValue:  "partner",
+			wantSource: SourceProject,
+		},
+		{
+			name:       "environment profile beats project profile",
+			project:    "profile: partner\n",
+			env:        map[string]string{"MYTODO_PROFILE": "other"},
+			key:        "profile",
+			wantValue:  "other",
+			wantSource: SourceEnv,
+		},
+		{
+			name:       "profile settings come from the profile only",
+			user:       "profile: partner\njira:\n  project: MAIN\nprofiles:\n  partner:\n    jira:\n      project: PART\n",
+			env:        map[string]string{"JIRA_PROJECT_KEY": "ENV"},
+			key:        "jira.project",
+			wantValue:  "PART",
+			wantSource: "user, profile partner",
+		},
+		{
+			name:       "profile without the setting does not fall back",
+			user:       "profile: partner\njira:\n  email: me@main\nprofiles:\n  partner:\n    jira:\n      project: PART\n",
+			key:        "jira.email",
+			wantValue:  "",
+			wantSource: "default, profile partner",
+		},
+		{
+			name:       "command setting beats general setting",
+			user:       "agent:\n  model: big\n  commands:\n    list:\n      model: small\n",
+			command:    "list",
+			key:        "agent.model",
+			wantValue:  "small",
+			wantSource: "user, command list",
+		},
+		{
+			name:       "other commands use the general setting",
+			user:       "agent:\n  model: big\n  commands:\n    list:\n      model: small\n",
+			command:    "standup",
+			key:        "agent.model",
+			wantValue:  "big",
+			wantSource: SourceUser,
+		},
+		{
+			name:       "flag beats command setting",
+			user:       "agent:\n  commands:\n    list:\n      model: small\n",
+			flags:      map[string]string{"agent.model": "flagged"},
+			command:    "list",
+			key:        "agent.model",
+			wantValue:  "flagged",
+			wantSource: SourceFlag,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := setup(t, tt.project, tt.user, tt.env); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			for name, value := range tt.flags {
+				SetFlag(name, value)
+			}
+
+			var value string
+			var source Source
+			if tt.command != "" {
+				value, source = LookupFor(tt.command, tt.key)
+			} else {
+				value, source = Lookup(tt.key)
+			}
+			if value != tt.wantValue || source != tt.wantSource {
+				t.Errorf("lookup of %s = %q (%s), want %q (%s)", tt.key, value, source, tt.wantValue, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestLoadProblems(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		user    string
+		wantErr bool
+		wantIs  error
+		// key is looked up after loading and must come from wantSource.
+		key        string
+		wantSource Source
+	}{
+		{
+			name:       "credential helper in project file",
+			project:    "jira:\n  project: PROJ\n  token_command: pass show jira\n",
+			wantErr:    true,
+			wantIs:     ErrUserOnly,
+			key:        "jira.project",
+			wantSource: SourceDefault,
+		},
+		{
+			name:       "secret in project file",
+			project:    "agent:\n  api_key: sk-123\n",
+			wantErr:    true,
+			wantIs:     ErrUserOnly,
+			key:        "agent.api_key",
+			wantSource: SourceDefault,
+		},
+		{
+			name:       "endpoint in project file",
+			project:    "agent:\n  commands:\n    list:\n      endpoint: https://example.com\n",
+			wantErr:    true,
+			wantIs:     ErrUserOnly,
+			key:        "agent.endpoint",
+			wantSource: SourceDefault,
+		},
+		{
+			name:       "profile URL in project file",
+			project:    "profile: evil\nprofiles:\n  evil:\n    jira:\n      url: https://example.com\n",
+			wantErr:    true,
+			wantIs:     ErrUserOnly,
+			key:        "profile",
+			wantSource: SourceDefault,
+		},
+		{
+			name:       "secrets are fine in the user file",
+			user:       "jira:\n  token_command: pass show jira\n",
+			key:        "jira.token_command",
+			wantSource: SourceUser,
+		},
+		{
+			name:       "unknown key leaves the whole file out",
+			user:       "jira:\n  project: USER\n  colour: blue\n",
+			wantErr:    true,
+			wantIs:     ErrUnknownKey,
+			key:        "jira.project",
+			wantSource: SourceDefault,
+		},
+		{
+			name:       "invalid value leaves the whole file out",
+			user:       "agent:\n  backend: gemini\n  model: x\n",
+			wantErr:    true,
+			key:        "agent.model",
+			wantSource: SourceDefault,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := setup(t, tt.project, tt.user, nil)
+			if tt.wantErr && err == nil {
+				t.Fatal("Load() succeeded, want an error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("Load() error = %v, want %v", err, tt.wantIs)
+			}
+			if _, source := Lookup(tt.key); source != tt.wantSource {
+				t.Errorf("source of %s = %s, want %s", tt.key, source, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestProjectPathsResolveAgainstTheFile(t *testing.T) {
+	projectDir, err := setup(t, "storage:\n  file: todo.json\n", "", nil)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	// The temp dir may be reached through a symlink; compare real paths.
+	got := Get("storage.file")
+	gotDir, _ := filepath.EvalSymlinks(filepath.Dir(got))
+	wantDir, _ := filepath.EvalSymlinks(projectDir)
+	if gotDir != wantDir || filepath.Base(got) != "todo.json" {
+		t.Errorf("storage.file = %q, want todo.json in %s", got, projectDir)
+	}
+}
+
+func TestSetKeepsOtherSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), UserFile)
+	writeFile(t, path, "# my settings\njira:\n  url: https://company.atlassian.net # work\n  project: PROJ\n")
+
+	steps := []struct {
+		name, value string
+	}{
+		{"jira.project", "NEW"},
+		{"profiles.partner.jira.url", "https://partner.atlassian.net"},
+		{"agent.backend", "ollama"},
+		{"agent.backend", ""},
+	}
+	for _, step := range steps {
+		if err := Set(path, step.name, step.value); err != nil {
+			t.Fatalf("Set(%s, %q) error = %v", step.name, step.value, err)
+		}
+	}
+	if err := Set(path, "agent.backend", "gemini"); err == nil {
+		t.Error("Set() accepted an invalid value")
+	}
+
+	values, err := ReadFile(path, SourceUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"jira.url":                  "https://company.atlassian.net",
+		"jira.project":              "NEW",
+		"profiles.partner.jira.url": "https://partner.atlassian.net",
+	}
+	if len(values) != len(want) {
+		t.Errorf("ReadFile() = %v, want %v", values, want)
+	}
+	for name, value := range want {
+		if values[name] != value {
+			t.Errorf("%s = %q, want %q", name, values[name], value)
+		}
+	}
+	content, _ := os.ReadFile(path)
+	for _, comment := range []string{"# my settings", "# work"} {
+		if !strings.Contains(string(content), comment) {
+			t.Errorf("comment %q was lost:\n%s", comment, content)
+		}
+	}
+}
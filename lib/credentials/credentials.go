@@ -0,0 +1,217 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mytodo/lib/vault"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Services whose secrets can be kept in the credentials file.
+const (
+	Jira   = "jira"
+	Quip   = "quip"
+	OpenAI = "openai"
+)
+
+var Services = []string{Jira, Quip, OpenAI}
+
+// Source tells where a resolved secret came from.
+type Source string
+
+const (
+	SourceNone   Source = ""
+	SourceConfig Source = "config"
+	SourceHelper Source = "helper"
+	SourceFile   Source = "credentials file"
+)
+
+// Path returns the location of the encrypted credentials file.
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mytodo", "credentials.enc"), nil
+}
+
+// Exists reports whether the credentials file has been created.
+func Exists() bool {
+	path, err := Path()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Store holds the secrets of the credentials file. Entries are named after
+// the service, with "@<profile>" for a profile other than the default one.
+type Store struct {
+	path    string
+	vault   *vault.Vault
+	secrets map[string]string
+}
+
+// Open unlocks the credentials file with the passphrase or key file used for
+// encrypted files (MYTODO_KEY_FILE, MYTODO_PASSPHRASE or a prompt). A missing
+// file gives an empty store, which asks for a new passphrase when saved.
+func Open() (*Store, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, secrets: map[string]string{}}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.vault, err = vault.OpenFor("credentials file", content); err != nil {
+		return nil, fmt.Errorf("unlocking %s: %w", path, err)
+	}
+	plain, err := s.vault.Decode(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(plain, &s.secrets); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func entryName(service, profile string) string {
+	if profile == "" {
+		return service
+	}
+	return service + "@" + profile
+}
+
+// Get returns the secret stored for a service and profile.
+func (s *Store) Get(service, profile string) (string, bool) {
+	secret, ok := s.secrets[entryName(service, profile)]
+	return secret, ok
+}
+
+// Set stores a secret; Save writes it.
+func (s *Store) Set(service, profile, secret string) {
+	s.secrets[entryName(service, profile)] = secret
+}
+
+// Delete removes a secret and reports whether there was one; Save writes the
+// change.
+func (s *Store) Delete(service, profile string) bool {
+	name := entryName(service, profile)
+	_, ok := s.secrets[name]
+	delete(s.secrets, name)
+	return ok
+}
+
+// Entries returns the names of the stored secrets, sorted.
+func (s *Store) Entries() []string {
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save encrypts and writes the store.
+func (s *Store) Save() error {
+	if s.vault == nil {
+		secret, err := vault.ResolveSecretFor("credentials file", true)
+		if err != nil {
+			return err
+		}
+		if s.vault, err = vault.New(secret); err != nil {
+			return err
+		}
+	}
+
+	plain, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+	content, err := s.vault.Encode(plain)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, content, 0600)
+}
+
+// RunHelper runs a credential helper, a shell command such as "pass show
+// jira", and returns the first line it prints. The helper can prompt on the
+// terminal; its stderr is passed through.
+func RunHelper(command string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, &stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("credential helper %q: %w", command, err)
+	}
+	secret, _, _ := strings.Cut(stdout.String(), "\n")
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return "", fmt.Errorf("credential helper %q printed nothing", command)
+	}
+	return secret, nil
+}
+
+// Resolved secrets, kept for the rest of the process so that helpers run and
+// the credentials file is unlocked at most once. Nothing is written to disk.
+var (
+	cache    = map[string]resolved{}
+	store    *Store
+	storeErr error
+)
+
+type resolved struct {
+	secret string
+	source Source
+	err    error
+}
+
+// Resolve returns the secret of a service: value when it is set, otherwise
+// what the helper command prints, otherwise the entry in the credentials
+// file. An empty secret with no error means none is configured.
+func Resolve(service, profile, value, command string) (string, Source, error) {
+	if value != "" {
+		return value, SourceConfig, nil
+	}
+
+	name := entryName(service, profile)
+	if r, ok := cache[name]; ok {
+		return r.secret, r.source, r.err
+	}
+
+	var r resolved
+	switch {
+	case command != "":
+		r.secret, r.err = RunHelper(command)
+		r.source = SourceHelper
+	case Exists():
+		if store == nil && storeErr == nil {
+			store, storeErr = Open()
+		}
+		if storeErr != nil {
+			r.err = storeErr
+		} else if secret, ok := store.Get(service, profile); ok {
+			r.secret, r.source = secret, SourceFile
+		}
+	}
+	cache[name] = r
+	return r.secret, r.source, r.err
+}
@@ -0,0 +1,182 @@
+package gitsync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Repo wraps the git repository holding the task file and its archive. All
+// operations shell out to the git binary so the user's credentials and config
+// apply.
+type Repo struct {
+	root    string // repository top-level directory
+	file    string // task file, relative to root
+	archive string // archive file next to it, relative to root
+}
+
+// Open finds the repository containing taskFile. archiveFile, which lives in
+// the same directory, is committed and merged along with it once it exists.
+func Open(taskFile, archiveFile string) (*Repo, error) {
+	abs, err := filepath.Abs(taskFile)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(abs)
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	out, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not inside a git repository: %w", dir, err)
+	}
+	root := strings.TrimSpace(out)
+
+	rel, err := filepath.Rel(root, filepath.Join(dir, filepath.Base(abs)))
+	if err != nil {
+		return nil, err
+	}
+	archiveRel, err := filepath.Rel(root, filepath.Join(dir, filepath.Base(archiveFile)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repo{root: root, file: filepath.ToSlash(rel), archive: filepath.ToSlash(archiveRel)}, nil
+}
+
+// paths returns the files to stage: the task file, and the archive when it
+// exists.
+func (r *Repo) paths() []string {
+	if _, err := os.Stat(filepath.Join(r.root, filepath.FromSlash(r.archive))); err == nil {
+		return []string{r.file, r.archive}
+	}
+	return []string{r.file}
+}
+
+// Root returns the repository top-level directory.
+func (r *Repo) Root() string {
+	return r.root
+}
+
+// CommitFile stages the task file and the archive and commits them if they
+// changed. It reports whether a commit was made.
+func (r *Repo) CommitFile(message string) (bool, error) {
+	paths := r.paths()
+	if _, err := r.git(append([]string{"add", "--"}, paths...)...); err != nil {
+		return false, err
+	}
+	if _, err := r.git(append([]string{"diff", "--cached", "--quiet", "--"}, paths...)...); err == nil {
+		return false, nil
+	}
+	if _, err := r.git(append([]string{"commit", "-m", message, "--"}, paths...)...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Upstream returns the tracking branch of HEAD, or "" when none is set.
+func (r *Repo) Upstream() string {
+	out, err := r.git("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+func (r *Repo) Fetch() error {
+	_, err := r.git("fetch", "--quiet")
+	return err
+}
+
+func (r *Repo) Push() error {
+	_, err := r.git("push", "--quiet")
+	return err
+}
+
+// IsAncestor reports whether commit a is reachable from commit b.
+func (r *Repo) IsAncestor(a, b string) bool {
+	_, err := r.git("merge-base", "--is-ancestor", a, b)
+	return err == nil
+}
+
+func (r *Repo) MergeBase(a, b string) (string, error) {
+	out, err := r.git("merge-base", a, b)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// ShowFile returns the task file content at the given revision. A revision
+// where the file does not exist yields empty content.
+func (r *Repo) ShowFile(rev string) ([]byte, error) {
+	return r.show(rev, r.file)
+}
+
+// ShowArchive is ShowFile for the archive file.
+func (r *Repo) ShowArchive(rev string) ([]byte, error) {
+	return r.show(rev, r.archive)
+}
+
+func (r *Repo) show(rev, path string) ([]byte, error) {
+	if _, err := r.git("cat-file", "-e", rev+":"+path); err != nil {
+		return nil, nil
+	}
+	out, err := r.git("show", rev+":"+path)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func (r *Repo) FastForward(rev string) error {
+	_, err := r.git("merge", "--ff-only", "--quiet", rev)
+	return err
+}
+
+// StartMerge merges rev without committing. Textual conflicts are resolved
+// in favour of HEAD; the caller is expected to overwrite the task file and
+// the archive with its own merge results and then call FinishMerge.
+func (r *Repo) StartMerge(rev string) error {
+	if _, err := r.git("merge", "--no-commit", "--no-ff", "-X", "ours", rev); err != nil {
+		r.git("merge", "--abort")
+		return err
+	}
+	return nil
+}
+
+func (r *Repo) FinishMerge() error {
+	if _, err := r.git(append([]string{"add", "--"}, r.paths()...)...); err != nil {
+		return err
+	}
+	_, err := r.git("commit", "--no-edit")
+	return err
+}
+
+func (r *Repo) AbortMerge() {
+	r.git("merge", "--abort")
+}
+
+func (r *Repo) git(args ...string) (string, error) {
+	return runGit(r.root, args...)
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return stdout.String(), fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return stdout.String(), fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,139 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/utils"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	EventEnvVar = "MYTODO_EVENT"
+
+	// Timeout bounds a single hook so a stuck script cannot hang mytodo.
+	Timeout = 30 * time.Second
+)
+
+// Events lists the events hooks can be installed for.
+var Events = []tasklist.Event{
+	tasklist.EventAdd,
+	tasklist.EventDone,
+	tasklist.EventEdit,
+	tasklist.EventComment,
+	tasklist.EventRemove,
+}
+
+// Runner runs the executables in a directory that are named after an event:
+// "on-add", or "on-add.<anything>" to install several, run in name order.
+// It implements tasklist.Hook.
+type Runner struct {
+	dir      string
+	taskFile string
+	output   io.Writer
+}
+
+// DefaultDir returns the hooks directory under the user config directory.
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mytodo", "hooks"), nil
+}
+
+// New returns a Runner for the hooks in dir. Hook output goes to stderr so
+// it cannot corrupt commands that speak a protocol on stdout.
+func New(dir, taskFile string) *Runner {
+	return &Runner{dir: dir, taskFile: taskFile, output: os.Stderr}
+}
+
+// Find returns the hooks installed for event, in the order they run.
+func (r *Runner) Find(event tasklist.Event) ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name != string(event) && !strings.HasPrefix(name, string(event)+".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+		paths = append(paths, filepath.Join(r.dir, name))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Run passes the task as JSON on stdin to every hook for event. The first
+// hook to exit non-zero vetoes the change.
+func (r *Runner) Run(event tasklist.Event, task *tasklist.Task) error {
+	paths, err := r.Find(event)
+	if err != nil {
+		return fmt.Errorf("finding %s hooks: %w", event, err)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := r.runOne(path, event, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) runOne(path string, event tasklist.Event, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = r.output
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), EventEnvVar+"="+string(event), utils.TaskFileEnvVar+"="+r.taskFile)
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("hook %s timed out after %s", filepath.Base(path), Timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = exitErr.Error()
+		}
+		return fmt.Errorf("%w %s: %s", tasklist.ErrRejected, filepath.Base(path), reason)
+	}
+	if err != nil {
+		return fmt.Errorf("running hook %s: %w", filepath.Base(path), err)
+	}
+	// On success stderr is just feedback; on failure it became the reason above.
+	r.output.Write(stderr.Bytes())
+	return nil
+}
@@ -0,0 +1,374 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mytodo/lib/tasklist"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ProdID = "-//mytodo//mytodo//EN"
+
+	dateTimeUTCLayout = "20060102T150405Z"
+	dateTimeLayout    = "20060102T150405"
+	dateLayout        = "20060102"
+
+	// RFC 5545 section 3.1: lines SHOULD NOT be longer than 75 octets.
+	maxLineOctets = 75
+)
+
+// Fields are the task fields, by JSON name, that a VTODO carries: SUMMARY,
+// STATUS, CREATED, COMPLETED, DUE, PRIORITY, CATEGORIES, RRULE, RELATED-TO
+// and COMMENT. Importing into an existing task only replaces these.
+var Fields = []string{"content", "done", "created_at", "completed_at", "due", "priority", "tags", "recurrence", "parent", "comments"}
+
+// property is a single unfolded content line: NAME;PARAM=VALUE:value
+type property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// -----------------------------------------------------------------------------
+// Export
+// -----------------------------------------------------------------------------
+
+// Encode writes the tasks as a VCALENDAR holding one VTODO per task.
+// The task ID is used as UID so a later import can match tasks back up.
+func Encode(w io.Writer, tasks []tasklist.Task) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format(dateTimeUTCLayout)
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:"+ProdID)
+
+	for _, task := range tasks {
+		writeLine(bw, "BEGIN:VTODO")
+		writeLine(bw, "UID:"+escapeText(task.ID))
+		writeLine(bw, "DTSTAMP:"+stamp)
+		writeLine(bw, "SUMMARY:"+escapeText(task.Content))
+		if task.Done {
+			writeLine(bw, "STATUS:COMPLETED")
+		} else {
+			writeLine(bw, "STATUS:NEEDS-ACTION")
+		}
+		if task.CreatedAt != nil {
+			writeLine(bw, "CREATED:"+task.CreatedAt.UTC().Format(dateTimeUTCLayout))
+		}
+		if task.CompletedAt != nil {
+			writeLine(bw, "COMPLETED:"+task.CompletedAt.UTC().Format(dateTimeUTCLayout))
+		}
+		if task.Due != nil {
+			writeLine(bw, formatDue(*task.Due))
+		}
+		if p := priorityToICal(task.Priority); p != 0 {
+			writeLine(bw, fmt.Sprintf("PRIORITY:%d", p))
+		}
+		if len(task.Tags) > 0 {
+			escaped := make([]string, 0, len(task.Tags))
+			for _, tag := range task.Tags {
+				escaped = append(escaped, escapeText(tag))
+			}
+			writeLine(bw, "CATEGORIES:"+strings.Join(escaped, ","))
+		}
+		if task.Recurrence != "" {
+			writeLine(bw, "RRULE:"+task.Recurrence)
+		}
+		if task.ParentID != "" {
+			writeLine(bw, "RELATED-TO;RELTYPE=PARENT:"+escapeText(task.ParentID))
+		}
+		for _, comment := range task.Comments {
+			writeLine(bw, "COMMENT:"+escapeText(comment.Text))
+		}
+		writeLine(bw, "END:VTODO")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// formatDue renders all-day dues as DATE values and everything else in UTC.
+func formatDue(due time.Time) string {
+	if due.Hour() == 0 && due.Minute() == 0 && due.Second() == 0 {
+		return "DUE;VALUE=DATE:" + due.Format(dateLayout)
+	}
+	return "DUE:" + due.UTC().Format(dateTimeUTCLayout)
+}
+
+// writeLine folds the line at 75 octets without splitting UTF-8 sequences
+// and terminates it with CRLF.
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards the limit.
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func escapeText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// priorityToICal maps H/M/L onto the RFC 5545 1-9 scale (0 = undefined).
+func priorityToICal(p tasklist.Priority) int {
+	switch p {
+	case tasklist.PriorityHigh:
+		return 1
+	case tasklist.PriorityMedium:
+		return 5
+	case tasklist.PriorityLow:
+		return 9
+	default:
+		return 0
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Import
+// -----------------------------------------------------------------------------
+
+// Decode reads every VTODO component in the stream and converts it to a
+// task. Components other than VTODO are skipped.
+func Decode(r io.Reader) ([]tasklist.Task, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []tasklist.Task
+	var current *tasklist.Task
+	depth := 0 // nesting inside the current VTODO (e.g. VALARM)
+
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		switch {
+		case prop.Name == "BEGIN" && strings.EqualFold(prop.Value, "VTODO"):
+			current = &tasklist.Task{}
+			depth = 0
+			continue
+		case prop.Name == "END" && strings.EqualFold(prop.Value, "VTODO"):
+			if current != nil {
+				tasks = append(tasks, *current)
+			}
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		if prop.Name == "BEGIN" {
+			depth++
+			continue
+		}
+		if prop.Name == "END" {
+			depth--
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+
+		if err := applyProperty(current, prop); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+	}
+
+	return tasks, nil
+}
+
+func applyProperty(task *tasklist.Task, prop property) error {
+	switch prop.Name {
+	case "UID":
+		task.ID = unescapeText(prop.Value)
+	case "SUMMARY":
+		task.Content = unescapeText(prop.Value)
+	case "STATUS":
+		task.Done = strings.EqualFold(prop.Value, "COMPLETED")
+	case "CREATED":
+		created, err := parseDateTime(prop)
+		if err != nil {
+			return fmt.Errorf("invalid CREATED: %w", err)
+		}
+		task.CreatedAt = &created
+	case "COMPLETED":
+		task.Done = true
+		completed, err := parseDateTime(prop)
+		if err != nil {
+			return fmt.Errorf("invalid COMPLETED: %w", err)
+		}
+		task.CompletedAt = &completed
+	case "DUE":
+		due, err := parseDateTime(prop)
+		if err != nil {
+			return fmt.Errorf("invalid DUE: %w", err)
+		}
+		task.Due = &due
+	case "PRIORITY":
+		p, err := strconv.Atoi(strings.TrimSpace(prop.Value))
+		if err != nil {
+			return fmt.Errorf("invalid PRIORITY: %w", err)
+		}
+		task.Priority = priorityFromICal(p)
+	case "CATEGORIES":
+		for _, tag := range splitUnescaped(prop.Value, ',') {
+			if tag = strings.TrimSpace(unescapeText(tag)); tag != "" {
+				task.Tags = append(task.Tags, tag)
+			}
+		}
+	case "RRULE":
+		task.Recurrence = prop.Value
+	case "RELATED-TO":
+		// RELTYPE defaults to PARENT (RFC 5545 section 3.2.15).
+		if rel := prop.Params["RELTYPE"]; rel == "" || strings.EqualFold(rel, "PARENT") {
+			task.ParentID = unescapeText(prop.Value)
+		}
+	case "COMMENT", "DESCRIPTION":
+		task.Comments = append(task.Comments, tasklist.Comment{Text: unescapeText(prop.Value)})
+	}
+	return nil
+}
+
+// priorityFromICal follows RFC 5545: 1-4 high, 5 medium, 6-9 low.
+func priorityFromICal(p int) tasklist.Priority {
+	switch {
+	case p >= 1 && p <= 4:
+		return tasklist.PriorityHigh
+	case p == 5:
+		return tasklist.PriorityMedium
+	case p >= 6 && p <= 9:
+		return tasklist.PriorityLow
+	default:
+		return tasklist.PriorityNone
+	}
+}
+
+func parseDateTime(prop property) (time.Time, error) {
+	value := strings.TrimSpace(prop.Value)
+
+	if strings.EqualFold(prop.Params["VALUE"], "DATE") || len(value) == len(dateLayout) {
+		return time.ParseInLocation(dateLayout, value, time.Local)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(dateTimeUTCLayout, value)
+	}
+
+	loc := time.Local
+	if tzid := prop.Params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation(dateTimeLayout, value, loc)
+}
+
+// unfold joins continuation lines (starting with space or tab) onto the
+// previous line, per RFC 5545 section 3.1.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading calendar: %w", err)
+	}
+	return lines, nil
+}
+
+func parseProperty(line string) (property, error) {
+	// The value starts at the first colon outside a quoted parameter value.
+	colon := -1
+	quoted := false
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon == -1 {
+		return property{}, fmt.Errorf("malformed content line: %q", line)
+	}
+
+	head := line[:colon]
+	prop := property{Value: line[colon+1:], Params: map[string]string{}}
+
+	parts := strings.Split(head, ";")
+	prop.Name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			prop.Params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return prop, nil
+}
+
+func unescapeText(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				sb.WriteByte('\n')
+			default:
+				sb.WriteByte(s[i])
+			}
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// splitUnescaped splits on sep, ignoring separators escaped with a backslash.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == sep {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,183 @@
+package ical
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mytodo/lib/tasklist"
+)
+
+func TestRoundTrip(t *testing.T) {
+	at := func(s string) *time.Time {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &parsed
+	}
+
+	tests := []struct {
+		name string
+		task tasklist.Task
+	}{
+		{
+			name: "minimal",
+			task: tasklist.Task{ID: "1", Content: "Buy milk"},
+		},
+		{
+			name: "every field a VTODO carries",
+			task: tasklist.Task{
+				ID:          "2",
+				Content:     "Ship release",
+				Done:        true,
+				CreatedAt:   at("2025-06-01T08:00:00Z"),
+				CompletedAt: at("2025-06-03T17:30:00Z"),
+				Due:         at("2025-06-04T12:15:00Z"),
+				Priority:    tasklist.PriorityHigh,
+				Tags:        []string{"work", "release"},
+				Recurrence:  "FREQ=WEEKLY;BYDAY=MO",
+				ParentID:    "1",
+				Comments:    []tasklist.Comment{{Text: "first"}, {Text: "second"}},
+			},
+		},
+		{
+			name: "all-day due date",
+			task: tasklist.Task{ID: "3", Content: "Holiday", Due: at("2025-12-24T00:00:00Z")},
+		},
+		{
+			name: "text that needs escaping",
+			task: tasklist.Task{
+				ID:       "4",
+				Content:  `Commas, semicolons; back\slashes`,
+				Tags:     []string{"a,b", "c;d"},
+				Comments: []tasklist.Comment{{Text: "line one\nline two"}},
+			},
+		},
+		{
+			name: "long summary is folded and unfolded",
+			task: tasklist.Task{ID: "5", Content: strings.Repeat("long ünïcödé text ", 12)},
+		},
+		{
+			name: "medium and low priority",
+			task: tasklist.Task{ID: "6", Content: "m", Priority: tasklist.PriorityMedium, Tags: []string{"x"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, []tasklist.Task{tt.task}); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+				if len(line) > maxLineOctets {
+					t.Errorf("line longer than %d octets: %q", maxLineOctets, line)
+				}
+			}
+
+			got, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("Decode() returned %d tasks, want 1", len(got))
+			}
+			want := tt.task
+			if want.Due != nil && want.Due.Hour() == 0 {
+				// All-day dues come back as local midnight.
+				local := time.Date(want.Due.Year(), want.Due.Month(), want.Due.Day(), 0, 0, 0, 0, time.Local)
+				want.Due = &local
+			}
+			if !sameTask(got[0], want) {
+				t.Errorf("round trip =\n  %+v\nwant\n  %+v", got[0], want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []tasklist.Task
+		wantErr bool
+	}{
+		{
+			name: "skips other components and alarms",
+			input: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:meeting\r\nEND:VEVENT\r\n" +
+				"BEGIN:VTODO\r\nUID:a\r\nSUMMARY:task\r\nBEGIN:VALARM\r\nDESCRIPTION:alarm\r\nEND:VALARM\r\nEND:VTODO\r\nEND:VCALENDAR\r\n",
+			want: []tasklist.Task{{ID: "a", Content: "task"}},
+		},
+		{
+			name:  "RFC 5545 priorities",
+			input: "BEGIN:VTODO\r\nUID:a\r\nPRIORITY:3\r\nEND:VTODO\r\nBEGIN:VTODO\r\nUID:b\r\nPRIORITY:5\r\nEND:VTODO\r\nBEGIN:VTODO\r\nUID:c\r\nPRIORITY:9\r\nEND:VTODO\r\n",
+			want: []tasklist.Task{
+				{ID: "a", Priority: tasklist.PriorityHigh},
+				{ID: "b", Priority: tasklist.PriorityMedium},
+				{ID: "c", Priority: tasklist.PriorityLow},
+			},
+		},
+		{
+			name:  "related-to of another type is no parent",
+			input: "BEGIN:VTODO\r\nUID:a\r\nRELATED-TO;RELTYPE=SIBLING:b\r\nEND:VTODO\r\n",
+			want:  []tasklist.Task{{ID: "a"}},
+		},
+		{
+			name:  "COMPLETED marks the task done",
+			input: "BEGIN:VTODO\r\nUID:a\r\nCOMPLETED:20250603T173000Z\r\nEND:VTODO\r\n",
+			want:  []tasklist.Task{{ID: "a", Done: true, CompletedAt: ptr(time.Date(2025, 6, 3, 17, 30, 0, 0, time.UTC))}},
+		},
+		{
+			name:    "invalid priority",
+			input:   "BEGIN:VTODO\r\nUID:a\r\nPRIORITY:high\r\nEND:VTODO\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid due",
+			input:   "BEGIN:VTODO\r\nUID:a\r\nDUE:tomorrow\r\nEND:VTODO\r\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Decode() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Decode() returned %d tasks, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !sameTask(got[i], tt.want[i]) {
+					t.Errorf("task %d =\n  %+v\nwant\n  %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+// sameTask compares tasks with times compared as instants.
+func sameTask(a, b tasklist.Task) bool {
+	for _, pair := range [][2]**time.Time{{&a.Due, &b.Due}, {&a.CreatedAt, &b.CreatedAt}, {&a.CompletedAt, &b.CompletedAt}} {
+		x, y := *pair[0], *pair[1]
+		if (x == nil) != (y == nil) || (x != nil && !x.Equal(*y)) {
+			return false
+		}
+		*pair[0], *pair[1] = nil, nil
+	}
+	return reflect.DeepEqual(a, b)
+}
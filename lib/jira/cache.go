@@ -0,0 +1,75 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxRecentEpics bounds the recent epic cache used for shell completion.
+const maxRecentEpics = 20
+
+// RecentEpic is an epic looked up before, remembered for completion.
+type RecentEpic struct {
+	Key     string    `json:"key"`
+	Summary string    `json:"summary"`
+	UsedAt  time.Time `json:"used_at"`
+}
+
+func recentEpicsPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mytodo", "recent-epics.json"), nil
+}
+
+// RecentEpics returns the cached epics, most recently used first. A missing
+// cache is not an error.
+func RecentEpics() ([]RecentEpic, error) {
+	path, err := recentEpicsPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var epics []RecentEpic
+	if err := json.Unmarshal(content, &epics); err != nil {
+		return nil, fmt.Errorf("reading recent epics: %w", err)
+	}
+	return epics, nil
+}
+
+// RememberEpic moves the epic to the front of the cache.
+func RememberEpic(key, summary string) error {
+	path, err := recentEpicsPath()
+	if err != nil {
+		return err
+	}
+
+	epics, _ := RecentEpics()
+	updated := []RecentEpic{{Key: key, Summary: summary, UsedAt: time.Now()}}
+	for _, epic := range epics {
+		if !strings.EqualFold(epic.Key, key) && len(updated) < maxRecentEpics {
+			updated = append(updated, epic)
+		}
+	}
+
+	content, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
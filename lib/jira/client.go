@@ -26,6 +26,14 @@ type Client struct {
 	baseURL  string
 	email    string
 	apiToken string
+
+	// Project is the key new issues are created in.
+	Project string
+	// Fields locates the site's custom fields.
+	Fields FieldMap
+	// Log receives progress messages of long searches; NewClient discards
+	// them.
+	Log io.Writer
 }
 
 func NewClient(baseURL, email, token string) *Client {
@@ -44,6 +52,7 @@ func NewClient(baseURL, email, token string) *Client {
 		baseURL:  baseURL,
 		email:    email,
 		apiToken: token,
+		Log:      io.Discard,
 	}
 }
 
@@ -123,7 +132,7 @@ func (c *Client) Create(summary, desc, issueType string, tags []string) (string,
 		Fields: &IssueFieldsScheme{
 			Summary: summary,
 			Project: &ProjectScheme{
-				Key: "PROJ",
+				Key: c.Project,
 			},
 			IssueType: &IssueTypeScheme{
 				Name: issueType,
@@ -152,7 +161,7 @@ func (c *Client) GetIssuesByEpic(epicKey string) ([]map[string]interface{}, erro
 
 	// Strategy 1: Search for child issues using parent field (modern JIRA)
 	jql := fmt.Sprintf("parent = %s", epicKey)
-	fmt.Printf("Searching with JQL: %s\n", jql)
+	fmt.Fprintf(c.Log, "Searching with JQL: %s\n", jql)
 
 	// Handle pagination - fetch all pages
 	startAt := 0
@@ -162,7 +171,7 @@ func (c *Client) GetIssuesByEpic(epicKey string) ([]map[string]interface{}, erro
 		// Use the new JQL endpoint
 		result, err := c.searchJQL(jql, startAt, maxResults)
 		if err != nil {
-			fmt.Printf("Warning: JQL search with 'parent' failed: %v\n", err)
+			fmt.Fprintf(c.Log, "Warning: JQL search with 'parent' failed: %v\n", err)
 			break
 		}
 
@@ -177,19 +186,12 @@ func (c *Client) GetIssuesByEpic(epicKey string) ([]map[string]interface{}, erro
 		startAt += maxResults
 	}
 
-	fmt.Printf("Found %d issues with 'parent' field\n", len(issueMap))
+	fmt.Fprintf(c.Log, "Found %d issues with 'parent' field\n", len(issueMap))
 
 	// Strategy 2: Try Epic Link field (legacy JIRA - field name varies by instance)
-	// Common Epic Link custom fields: customfield_10014, customfield_10008
-	epicLinkFields := []string{
-		"\"Epic Link\"",
-		"cf[10014]",
-		"cf[10008]",
-	}
-
-	for _, epicLinkField := range epicLinkFields {
+	for _, epicLinkField := range c.Fields.EpicLinkClauses() {
 		jql2 := fmt.Sprintf("%s = %s", epicLinkField, epicKey)
-		fmt.Printf("Trying JQL: %s\n", jql2)
+		fmt.Fprintf(c.Log, "Trying JQL: %s\n", jql2)
 
 		startAt = 0
 		for {
@@ -197,7 +199,7 @@ func (c *Client) GetIssuesByEpic(epicKey string) ([]map[string]interface{}, erro
 			result, err := c.searchJQL(jql2, startAt, maxResults)
 			if err != nil {
 				// This field might not exist, try next one
-				fmt.Printf("Warning: JQL search with '%s' failed: %v\n", epicLinkField, err)
+				fmt.Fprintf(c.Log, "Warning: JQL search with '%s' failed: %v\n", epicLinkField, err)
 				break
 			}
 
@@ -209,7 +211,7 @@ func (c *Client) GetIssuesByEpic(epicKey string) ([]map[string]interface{}, erro
 				}
 			}
 
-			fmt.Printf("Found %d new issues with '%s' field\n", foundInThisQuery, epicLinkField)
+			fmt.Fprintf(c.Log, "Found %d new issues with '%s' field\n", foundInThisQuery, epicLinkField)
 
 			// Check if we've fetched all results
 			if startAt+len(result.Issues) >= result.Total {
@@ -247,7 +249,7 @@ func (c *Client) GetIssuesByEpic(epicKey string) ([]map[string]interface{}, erro
 		result = append(result, issue)
 	}
 
-	fmt.Printf("Total unique issues found: %d\n", len(result))
+	fmt.Fprintf(c.Log, "Total unique issues found: %d\n", len(result))
 	return result, nil
 }
 
@@ -277,11 +279,9 @@ func (c *Client) GetIssue(issueKey string) (map[string]interface{}, error) {
 						// if !interestedCustomField(key) {
 						// 	continue
 						// }
-						fmt.Printf("Key %s\n", key)
 						// Only add custom fields that are not already present
 						if _, exists := existingFields[key]; !exists && value != nil {
 							existingFields[key] = value
-							fmt.Printf("Extracted custom field from raw response: %s = %v\n", key, value)
 						}
 
 					}
@@ -0,0 +1,48 @@
+package jira
+
+import "strings"
+
+// FieldMap names the custom fields whose IDs differ between JIRA sites, e.g.
+// "customfield_10016". Empty entries fall back to the common IDs.
+type FieldMap struct {
+	StoryPoints string
+	EpicLink    string
+	QADate      string
+}
+
+// Points returns the story points in an issue's fields.
+func (m FieldMap) Points(fields map[string]interface{}) (float64, bool) {
+	candidates := []string{"customfield_10013", "customfield_10016"}
+	if m.StoryPoints != "" {
+		candidates = []string{m.StoryPoints}
+	}
+	for _, id := range candidates {
+		if points, ok := fields[id].(float64); ok {
+			return points, true
+		}
+	}
+	return 0, false
+}
+
+// ExpectedQADate returns the expected QA date in an issue's fields.
+func (m FieldMap) ExpectedQADate(fields map[string]interface{}) (string, bool) {
+	id := m.QADate
+	if id == "" {
+		id = "customfield_10020"
+	}
+	date, ok := fields[id].(string)
+	return date, ok
+}
+
+// EpicLinkClauses returns the JQL field names to try when looking for issues
+// linked to an epic through an Epic Link field.
+func (m FieldMap) EpicLinkClauses() []string {
+	if m.EpicLink != "" {
+		return []string{"cf[" + strings.TrimPrefix(m.EpicLink, "customfield_") + "]"}
+	}
+	return []string{
+		"\"Epic Link\"",
+		"cf[10014]",
+		"cf[10008]",
+	}
+}
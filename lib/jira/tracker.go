@@ -8,21 +8,54 @@ import (
 
 // TicketRow represents a single row in the project tracker table
 type TicketRow struct {
-	Ticket              string
-	Description         string
-	Owner               string
-	ExpectedQADate      string
-	PercentCompletion   float64
-	PercentWeight       float64
-	DaysToQAEstimated   float64
-	DaysToQAActual      float64
-	ReqNumber           string
-	Status              string
-	ExtDependencies     string
+	Ticket            string  `json:"ticket"`
+	Description       string  `json:"description"`
+	Owner             string  `json:"owner"`
+	ExpectedQADate    string  `json:"expected_qa_date"`
+	PercentCompletion float64 `json:"percent_completion"`
+	PercentWeight     float64 `json:"percent_weight"`
+	DaysToQAEstimated float64 `json:"days_to_qa_estimated"`
+	DaysToQAActual    float64 `json:"days_to_qa_actual"`
+	ReqNumber         string  `json:"req_number"`
+	Status            string  `json:"status"`
+	ExtDependencies   string  `json:"ext_dependencies"`
 }
 
-// ExtractTicketRow extracts tracker information from a JIRA issue
-func ExtractTicketRow(issue map[string]interface{}) *TicketRow {
+// Tracker is an epic with its tracker rows, for structured output
+type Tracker struct {
+	EpicKey  string       `json:"epic_key"`
+	EpicName string       `json:"epic_name"`
+	Rows     []*TicketRow `json:"rows"`
+}
+
+// EpicProgress is the story count and points of one epic in a project
+type EpicProgress struct {
+	Key             string  `json:"key"`
+	Summary         string  `json:"summary"`
+	Stories         int     `json:"stories"`
+	Done            int     `json:"done"`
+	Pending         int     `json:"pending"`
+	EstimatedPoints float64 `json:"estimated_points"`
+}
+
+// AssignWeights sets each row's PercentWeight from its share of the
+// estimated days, as the table formatters do
+func AssignWeights(rows []*TicketRow) {
+	totalDays := 0.0
+	for _, row := range rows {
+		totalDays += row.DaysToQAEstimated
+	}
+	if totalDays == 0 {
+		return
+	}
+	for _, row := range rows {
+		row.PercentWeight = (row.DaysToQAEstimated / totalDays) * 100.0
+	}
+}
+
+// ExtractTicketRow extracts tracker information from a JIRA issue, reading
+// custom fields through the site's field map
+func ExtractTicketRow(issue map[string]interface{}, fieldMap FieldMap) *TicketRow {
 	fields, ok := issue["fields"].(map[string]interface{})
 	if !ok {
 		return nil
@@ -60,19 +93,11 @@ func ExtractTicketRow(issue map[string]interface{}) *TicketRow {
 		}
 	}
 
-	// Extract custom fields (these may vary by JIRA configuration)
-	// Expected QA Date - typically a custom field like customfield_XXXXX
-	if qaDate, ok := fields["customfield_10020"].(string); ok {
+	// Extract custom fields (these vary by JIRA configuration)
+	if qaDate, ok := fieldMap.ExpectedQADate(fields); ok {
 		row.ExpectedQADate = qaDate
 	}
-
-	// Story points - the custom field ID varies by JIRA instance
-	// Common IDs: customfield_10013, customfield_10016, customfield_10024
-	// Try customfield_10013 first (your instance's story point field)
-	if storyPoints, ok := fields["customfield_10013"].(float64); ok {
-		row.DaysToQAEstimated = storyPoints
-	} else if storyPoints, ok := fields["customfield_10016"].(float64); ok {
-		// Fallback to another common story point field
+	if storyPoints, ok := fieldMap.Points(fields); ok {
 		row.DaysToQAEstimated = storyPoints
 	}
 
@@ -0,0 +1,222 @@
+package listformat
+
+import (
+	"errors"
+	"fmt"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/utils"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+var (
+	ErrNotFound = errors.New("list format not found")
+
+	namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+)
+
+// Builtin formats, available without any setup. A file of the same name in
+// Dir takes precedence.
+var Builtin = map[string]string{
+	"oneline": `{{.Number}}. {{if .Done}}{{green "✔"}}{{else}}{{cyan "·"}}{{end}} {{.Content}}{{with .Due}} {{gray (print "(" (relative .) ")")}}{{end}}`,
+	"tmux":    `{{if not .Done}}{{trunc 30 .Content}}{{end}}`,
+	"review": `{{bold (print .Number ". " .Content)}}{{if .Done}} {{green "(done)"}}{{end}}
+  created {{ago .CreatedAt}}{{with .UpdatedAt}}, changed {{ago .}}{{end}}{{with .Due}}, due {{relative .}}{{end}}{{with .Priority}}, priority {{.}}{{end}}{{with .Tags}}, {{join ", " .}}{{end}}
+{{- range .Comments}}
+  - {{.Text}}{{with .At}} {{gray (ago .)}}{{end}}
+{{- end}}`,
+}
+
+// Task is what a format is executed with: the task and its number in the list.
+type Task struct {
+	Number int
+	tasklist.Task
+}
+
+// Dir returns the directory user formats are stored in, one <name>.tmpl file
+// per format.
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mytodo", "formats"), nil
+}
+
+// Names returns the builtin and stored format names, sorted.
+func Names() []string {
+	seen := map[string]bool{}
+	for name := range Builtin {
+		seen[name] = true
+	}
+	if dir, err := Dir(); err == nil {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if name, ok := strings.CutSuffix(entry.Name(), ".tmpl"); ok && !entry.IsDir() {
+				seen[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load returns the text of a named format.
+func Load(name string) (string, error) {
+	if !namePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid format name %q", name)
+	}
+	if dir, err := Dir(); err == nil {
+		data, err := os.ReadFile(filepath.Join(dir, name+".tmpl"))
+		if err == nil {
+			return strings.TrimRight(string(data), "\n"), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	if text, ok := Builtin[name]; ok {
+		return text, nil
+	}
+	return "", fmt.Errorf("%w: %s (available: %s)", ErrNotFound, name, strings.Join(Names(), ", "))
+}
+
+// Parse compiles a --template value: inline template text when it contains
+// "{{", otherwise the name of a stored or builtin format.
+func Parse(value string) (*template.Template, error) {
+	text := value
+	if !strings.Contains(value, "{{") {
+		var err error
+		if text, err = Load(value); err != nil {
+			return nil, err
+		}
+	}
+	tmpl, err := template.New("list").Funcs(Funcs(time.Now())).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Funcs returns the helpers available to formats. Relative dates are computed
+// against now.
+func Funcs(now time.Time) template.FuncMap {
+	colorFunc := func(attrs ...color.Attribute) func(...interface{}) string {
+		return color.New(attrs...).SprintFunc()
+	}
+	return template.FuncMap{
+		// Colors; left out when stdout is not a terminal or NO_COLOR is set.
+		"red":     colorFunc(color.FgRed),
+		"green":   colorFunc(color.FgGreen),
+		"yellow":  colorFunc(color.FgYellow),
+		"blue":    colorFunc(color.FgBlue),
+		"magenta": colorFunc(color.FgMagenta),
+		"cyan":    colorFunc(color.FgCyan),
+		"gray":    colorFunc(color.FgHiBlack),
+		"bold":    colorFunc(color.Bold),
+
+		// Dates. All accept a time or a (possibly nil) *time.Time.
+		"date": func(v interface{}) string {
+			if t, ok := timeOf(v); ok {
+				return utils.FormatDate(t)
+			}
+			return ""
+		},
+		"relative": func(v interface{}) string {
+			if t, ok := timeOf(v); ok {
+				return Relative(t, now)
+			}
+			return ""
+		},
+		"ago": func(v interface{}) string {
+			if t, ok := timeOf(v); ok {
+				return Relative(t, now)
+			}
+			return "at an unknown time"
+		},
+
+		// Text.
+		"trunc": Truncate,
+		"pad": func(width int, s string) string {
+			if n := utf8.RuneCountInString(s); n < width {
+				return s + strings.Repeat(" ", width-n)
+			}
+			return s
+		},
+		"join":  func(sep string, items []string) string { return strings.Join(items, sep) },
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}
+}
+
+func timeOf(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t != nil {
+			return *t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Relative describes t from the point of view of now in whole days: "today",
+// "tomorrow", "in 3d", "2w ago", ...
+func Relative(t, now time.Time) string {
+	t, now = t.Local(), now.Local()
+	// Local calendar dates, compared in UTC, where every day has 24 hours.
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+	days := int(day(t).Sub(day(now)).Hours() / 24)
+
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "tomorrow"
+	case -1:
+		return "yesterday"
+	}
+
+	n := days
+	if n < 0 {
+		n = -n
+	}
+	var amount string
+	switch {
+	case n < 14:
+		amount = fmt.Sprintf("%dd", n)
+	case n < 60:
+		amount = fmt.Sprintf("%dw", n/7)
+	case n < 730:
+		amount = fmt.Sprintf("%dmo", n/30)
+	default:
+		amount = fmt.Sprintf("%dy", n/365)
+	}
+	if days > 0 {
+		return "in " + amount
+	}
+	return amount + " ago"
+}
+
+// Truncate shortens s to at most width runes, ending with "…" when cut.
+func Truncate(width int, s string) string {
+	if width < 1 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,375 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mytodo/lib/service"
+	"mytodo/lib/tasklist"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Protocol versions this server can speak, newest first.
+var supportedVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+const (
+	tasksURI      = "mytodo://tasks"
+	taskURIPrefix = "mytodo://tasks/"
+
+	// JSON-RPC 2.0 error codes
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server speaks the Model Context Protocol over newline-delimited JSON-RPC,
+// exposing the task list as resources and a handful of tools.
+type Server struct {
+	svc     *service.Service
+	version string
+
+	mu  sync.Mutex // serialises writes to out
+	out io.Writer
+}
+
+func NewServer(svc *service.Service, version string) *Server {
+	return &Server{svc: svc, version: version}
+}
+
+// Serve reads requests from in until EOF and writes responses to out.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			s.write(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+
+		// Notifications carry no ID and never get a response.
+		if req.ID == nil {
+			continue
+		}
+
+		result, rpcErr := s.dispatch(req)
+		s.write(response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+	}
+
+	return scanner.Err()
+}
+
+func (s *Server) write(resp response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		b, _ = json.Marshal(response{JSONRPC: "2.0", ID: resp.ID, Error: &rpcError{Code: codeInternalError, Message: err.Error()}})
+	}
+	s.out.Write(append(b, '\n'))
+}
+
+func (s *Server) dispatch(req request) (interface{}, *rpcError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "jsonrpc must be 2.0"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return s.initialize(req.Params)
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": toolDefinitions}, nil
+	case "tools/call":
+		return s.callTool(req.Params)
+	case "resources/list":
+		return s.listResources()
+	case "resources/read":
+		return s.readResource(req.Params)
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	version := supportedVersions[0]
+	for _, v := range supportedVersions {
+		if v == p.ProtocolVersion {
+			version = v
+		}
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools":     map[string]interface{}{},
+			"resources": map[string]interface{}{},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    "mytodo",
+			"version": s.version,
+		},
+		"instructions": "Tasks from the user's mytodo list. Use search_tasks to find task IDs before completing or commenting on a task.",
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+// Resources
+// -----------------------------------------------------------------------------
+
+func (s *Server) listResources() (interface{}, *rpcError) {
+	tasks, err := s.svc.List(service.Filter{})
+	if err != nil {
+		return nil, &rpcError{Code: codeInternalError, Message: err.Error()}
+	}
+
+	resources := []map[string]interface{}{{
+		"uri":         tasksURI,
+		"name":        "All tasks",
+		"description": "Every task in the mytodo list",
+		"mimeType":    "application/json",
+	}}
+	for _, task := range tasks {
+		resources = append(resources, map[string]interface{}{
+			"uri":      taskURIPrefix + task.ID,
+			"name":     task.Content,
+			"mimeType": "application/json",
+		})
+	}
+
+	return map[string]interface{}{"resources": resources}, nil
+}
+
+func (s *Server) readResource(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	var payload interface{}
+	var err error
+	switch {
+	case p.URI == tasksURI:
+		payload, err = s.svc.List(service.Filter{})
+	case strings.HasPrefix(p.URI, taskURIPrefix):
+		payload, err = s.svc.Get(strings.TrimPrefix(p.URI, taskURIPrefix))
+	default:
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown resource: " + p.URI}
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "resource not found: " + p.URI}
+	}
+	if err != nil {
+		return nil, &rpcError{Code: codeInternalError, Message: err.Error()}
+	}
+
+	text, _ := json.MarshalIndent(payload, "", "  ")
+	return map[string]interface{}{
+		"contents": []map[string]interface{}{{
+			"uri":      p.URI,
+			"mimeType": "application/json",
+			"text":     string(text),
+		}},
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+// Tools
+// -----------------------------------------------------------------------------
+
+var toolDefinitions = []map[string]interface{}{
+	{
+		"name":        "add_task",
+		"description": "Add a task to the user's TODO list.",
+		"inputSchema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"content":  map[string]interface{}{"type": "string", "description": "What needs to be done"},
+				"due":      map[string]interface{}{"type": "string", "description": "Due date, YYYY-MM-DD or RFC 3339"},
+				"priority": map[string]interface{}{"type": "string", "enum": []string{"H", "M", "L"}},
+				"tags":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			},
+			"required": []string{"content"},
+		},
+	},
+	{
+		"name":        "complete_task",
+		"description": "Mark a task as done.",
+		"inputSchema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id": map[string]interface{}{"type": "string", "description": "Task ID"},
+			},
+			"required": []string{"id"},
+		},
+	},
+	{
+		"name":        "comment_task",
+		"description": "Add a comment to a task.",
+		"inputSchema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id":      map[string]interface{}{"type": "string", "description": "Task ID"},
+				"comment": map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"id", "comment"},
+		},
+	},
+	{
+		"name":        "search_tasks",
+		"description": "Search tasks by text, tag and completion state. Returns matching tasks with their IDs.",
+		"inputSchema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{"type": "string", "description": "Case-insensitive text to look for in the task content"},
+				"tag":   map[string]interface{}{"type": "string"},
+				"done":  map[string]interface{}{"type": "boolean"},
+			},
+		},
+	},
+}
+
+func (s *Server) callTool(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	if len(p.Arguments) == 0 {
+		p.Arguments = json.RawMessage("{}")
+	}
+
+	var result interface{}
+	var err error
+
+	switch p.Name {
+	case "add_task":
+		result, err = s.addTask(p.Arguments)
+	case "complete_task":
+		var args struct {
+			ID string `json:"id"`
+		}
+		if err = json.Unmarshal(p.Arguments, &args); err == nil {
+			result, err = s.svc.Complete(args.ID, "")
+		}
+	case "comment_task":
+		var args struct {
+			ID      string `json:"id"`
+			Comment string `json:"comment"`
+		}
+		if err = json.Unmarshal(p.Arguments, &args); err == nil {
+			result, err = s.svc.Comment(args.ID, "", args.Comment)
+		}
+	case "search_tasks":
+		var args struct {
+			Query string `json:"query"`
+			Tag   string `json:"tag"`
+			Done  *bool  `json:"done"`
+		}
+		if err = json.Unmarshal(p.Arguments, &args); err == nil {
+			result, err = s.svc.List(service.Filter{Query: args.Query, Tag: args.Tag, Done: args.Done})
+		}
+	default:
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + p.Name}
+	}
+
+	// Tool failures are reported to the model, not as protocol errors.
+	if err != nil {
+		return toolResult(err.Error(), true), nil
+	}
+	text, _ := json.MarshalIndent(result, "", "  ")
+	return toolResult(string(text), false), nil
+}
+
+func (s *Server) addTask(arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		Content  string   `json:"content"`
+		Due      string   `json:"due"`
+		Priority string   `json:"priority"`
+		Tags     []string `json:"tags"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	task := tasklist.Task{
+		Content:  args.Content,
+		Priority: tasklist.Priority(args.Priority),
+		Tags:     args.Tags,
+	}
+	if args.Due != "" {
+		due, err := parseDue(args.Due)
+		if err != nil {
+			return nil, err
+		}
+		task.Due = &due
+	}
+
+	return s.svc.Create(task)
+}
+
+func parseDue(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid due date %q: use YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
+
+func toolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]interface{}{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
@@ -0,0 +1,92 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+)
+
+// Format selects how commands write their results.
+type Format string
+
+const (
+	Plain Format = "plain" // the usual human-friendly output
+	Table Format = "table" // aligned columns, one row per item
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+)
+
+var Formats = []Format{Plain, Table, JSON, YAML}
+
+// Parse validates a --output value.
+func Parse(value string) (Format, error) {
+	for _, f := range Formats {
+		if Format(strings.ToLower(value)) == f {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q: use plain, table, json or yaml", value)
+}
+
+// Structured reports whether f is meant for programs rather than people.
+func (f Format) Structured() bool {
+	return f == JSON || f == YAML
+}
+
+// Apply makes f the output format of the process. Structured output is never
+// colored; otherwise color follows fatih/color, which already leaves it out
+// when stdout is not a terminal or NO_COLOR is set.
+func (f Format) Apply() {
+	if f.Structured() {
+		color.NoColor = true
+	}
+}
+
+// Encode writes v as JSON or YAML. YAML is produced from the JSON encoding,
+// so both use the same field names and the same field order.
+func Encode(w io.Writer, f Format, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if f == JSON {
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle drops the flow style and quoting the JSON input left on the
+// nodes; the encoder still quotes strings that would otherwise be misread.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// WriteTable writes rows as tab-aligned columns under an upper-case header.
+func WriteTable(w io.Writer, header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
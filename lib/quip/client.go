@@ -94,7 +94,7 @@ func (q *Client) postJson(resource string, params map[string]interface{}) []byte
 func (q *Client) getJson(resource string, params map[string]interface{}) []byte {
 	qs, err := ioutil.ReadAll(mapToQueryString(params))
 	if err != nil {
-		log.Fatal("Malformed query params %v", params)
+		log.Fatalf("Malformed query params %v", params)
 	}
 
 	queryString := string(qs)
@@ -115,7 +115,8 @@ func (q *Client) doRequest(req *http.Request) []byte {
 	req.Header.Set("Authorization", "Bearer "+q.accessToken)
 	res, err := client.Do(req)
 	if err != nil {
-		// TODO: handle API errors here
+		// TODO: surface API errors to callers instead of an empty body
+		return nil
 	}
 
 	defer res.Body.Close()
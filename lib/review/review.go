@@ -0,0 +1,81 @@
+package review
+
+import (
+	"mytodo/lib/tasklist"
+	"time"
+)
+
+// Kind names a step of the review.
+type Kind string
+
+const (
+	Stale       Kind = "stale"
+	Overdue     Kind = "overdue"
+	Unorganized Kind = "unorganized"
+	Completed   Kind = "completed"
+)
+
+// Step is one stage of the review with the IDs of the tasks it covers.
+// IDs rather than indexes, since acting on one task can renumber the rest.
+type Step struct {
+	Kind  Kind
+	Title string
+	IDs   []string
+}
+
+// Plan splits the list into the review steps, in the order they are walked:
+//   - Stale: pending tasks not touched for staleDays. Tasks from before
+//     timestamps were recorded count as stale.
+//   - Overdue: pending tasks due before today.
+//   - Unorganized: pending tasks with neither tags nor a due date.
+//   - Completed: done tasks, to archive.
+//
+// Each task shows up once: overdue wins over stale, and stale over
+// unorganized. Snoozed tasks were deferred already and are left alone.
+// Steps with no tasks are dropped.
+func Plan(tasks []tasklist.Task, now time.Time, staleDays int) []Step {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	cutoff := today.AddDate(0, 0, -staleDays)
+
+	steps := []Step{
+		{Kind: Stale, Title: "Stale tasks"},
+		{Kind: Overdue, Title: "Overdue tasks"},
+		{Kind: Unorganized, Title: "Tasks without tags or a due date"},
+		{Kind: Completed, Title: "Completed tasks to archive"},
+	}
+	for _, task := range tasks {
+		var kind Kind
+		switch {
+		case task.Done:
+			kind = Completed
+		case task.IsSnoozed(now):
+			continue
+		case task.Due != nil && task.Due.Before(today):
+			kind = Overdue
+		case isStale(&task, cutoff):
+			kind = Stale
+		case len(task.Tags) == 0 && task.Due == nil:
+			kind = Unorganized
+		default:
+			continue
+		}
+		for i := range steps {
+			if steps[i].Kind == kind {
+				steps[i].IDs = append(steps[i].IDs, task.ID)
+			}
+		}
+	}
+
+	var planned []Step
+	for _, step := range steps {
+		if len(step.IDs) > 0 {
+			planned = append(planned, step)
+		}
+	}
+	return planned
+}
+
+func isStale(task *tasklist.Task, cutoff time.Time) bool {
+	touched := task.LastTouched()
+	return touched == nil || touched.Before(cutoff)
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mytodo/lib/service"
+	"mytodo/lib/tasklist"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Server exposes a service.Service as a JSON HTTP API:
+//
+//	GET    /tasks                  list tasks (?done=true|false&tag=&q=)
+//	POST   /tasks                  create a task
+//	GET    /tasks/{id}             get a task
+//	PATCH  /tasks/{id}             update fields of a task (JSON merge patch)
+//	DELETE /tasks/{id}             delete a task
+//	POST   /tasks/{id}/complete    mark a task as done
+//	POST   /tasks/{id}/comments    add a comment: {"comment": "..."}
+//
+// Single-task responses carry an ETag header. Mutations honour If-Match and
+// answer 412 Precondition Failed when the task changed in the meantime.
+// Every request must send "Authorization: Bearer <token>".
+type Server struct {
+	svc   *service.Service
+	token string
+	mux   *http.ServeMux
+}
+
+func New(svc *service.Service, token string) *Server {
+	s := &Server{
+		svc:   svc,
+		token: token,
+		mux:   http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /tasks", s.handleList)
+	s.mux.HandleFunc("POST /tasks", s.handleCreate)
+	s.mux.HandleFunc("GET /tasks/{id}", s.handleGet)
+	s.mux.HandleFunc("PATCH /tasks/{id}", s.handleUpdate)
+	s.mux.HandleFunc("DELETE /tasks/{id}", s.handleDelete)
+	s.mux.HandleFunc("POST /tasks/{id}/complete", s.handleComplete)
+	s.mux.HandleFunc("POST /tasks/{id}/comments", s.handleComment)
+
+	return s
+}
+
+// Handler returns the authenticated HTTP handler.
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mytodo"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		s.mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := service.Filter{
+		Tag:   query.Get("tag"),
+		Query: query.Get("q"),
+	}
+	if done := query.Get("done"); done != "" {
+		v, err := strconv.ParseBool(done)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid done filter: %q", done))
+			return
+		}
+		filter.Done = &v
+	}
+
+	tasks, err := s.svc.List(filter)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tasks": tasks})
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var task tasklist.Task
+	if err := decodeBody(r, &task); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := s.svc.Create(task)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/tasks/"+created.ID)
+	writeTask(w, http.StatusCreated, created)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	task, err := s.svc.Get(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" && match == service.ETag(task) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	var patch map[string]json.RawMessage
+	if err := decodeBody(r, &patch); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	task, err := s.svc.Update(r.PathValue("id"), r.Header.Get("If-Match"), func(task *tasklist.Task) error {
+		return applyMergePatch(task, patch)
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if err := s.svc.Delete(r.PathValue("id"), r.Header.Get("If-Match")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
+	task, err := s.svc.Complete(r.PathValue("id"), r.Header.Get("If-Match"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (s *Server) handleComment(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Comment string `json:"comment"`
+	}
+	if err := decodeBody(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	task, err := s.svc.Comment(r.PathValue("id"), r.Header.Get("If-Match"), body.Comment)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+// applyMergePatch applies an RFC 7396 style patch: keys present in the patch
+// replace the task's fields, null removes them.
+func applyMergePatch(task *tasklist.Task, patch map[string]json.RawMessage) error {
+	current, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(current, &fields); err != nil {
+		return err
+	}
+
+	for key, value := range patch {
+		if string(value) == "null" {
+			delete(fields, key)
+		} else {
+			fields[key] = value
+		}
+	}
+
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	var updated tasklist.Task
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&updated); err != nil {
+		return fmt.Errorf("%w: %v", service.ErrInvalidTask, err)
+	}
+	*task = updated
+	return nil
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return nil
+}
+
+func writeTask(w http.ResponseWriter, status int, task tasklist.Task) {
+	w.Header().Set("ETag", service.ETag(task))
+	writeJSON(w, status, task)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrPreconditionFailed):
+		writeError(w, http.StatusPreconditionFailed, err)
+	case errors.Is(err, service.ErrConflict):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, service.ErrInvalidTask), errors.Is(err, tasklist.ErrRejected):
+		writeError(w, http.StatusUnprocessableEntity, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
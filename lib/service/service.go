@@ -0,0 +1,210 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mytodo/lib/tasklist"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNotFound           = errors.New("task not found")
+	ErrPreconditionFailed = errors.New("task was modified since it was read")
+	ErrConflict           = errors.New("a task with this ID already exists")
+	ErrInvalidTask        = errors.New("invalid task")
+)
+
+// Service serialises access to a TaskList so it can be shared by concurrent
+// callers such as HTTP handlers. Every call re-reads the task file first so
+// changes made by the CLI in the meantime are not overwritten.
+type Service struct {
+	mu    sync.Mutex
+	tasks *tasklist.TaskList
+}
+
+// Filter narrows down List results. Zero values match everything.
+type Filter struct {
+	Done  *bool
+	Tag   string
+	Query string // case-insensitive substring of the content
+}
+
+func New(tasks *tasklist.TaskList) *Service {
+	return &Service{tasks: tasks}
+}
+
+// ETag returns a strong entity tag derived from the task content.
+func ETag(task tasklist.Task) string {
+	b, _ := json.Marshal(task)
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func (s *Service) List(filter Filter) ([]tasklist.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.tasks.Reload(); err != nil {
+		return nil, err
+	}
+
+	result := []tasklist.Task{}
+	for _, task := range s.tasks.GetAllTasks() {
+		if filter.matches(task) {
+			result = append(result, task)
+		}
+	}
+	return result, nil
+}
+
+func (s *Service) Get(id string) (tasklist.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.tasks.Reload(); err != nil {
+		return tasklist.Task{}, err
+	}
+
+	index := s.tasks.FindTask(id)
+	if index == -1 {
+		return tasklist.Task{}, ErrNotFound
+	}
+	return *s.tasks.GetTask(index), nil
+}
+
+func (s *Service) Create(task tasklist.Task) (tasklist.Task, error) {
+	if err := validate(task); err != nil {
+		return tasklist.Task{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.tasks.Reload(); err != nil {
+		return tasklist.Task{}, err
+	}
+	if task.ID != "" && s.tasks.FindTask(task.ID) != -1 {
+		return tasklist.Task{}, ErrConflict
+	}
+	if err := s.tasks.AddTask(&task); err != nil {
+		return tasklist.Task{}, err
+	}
+	return task, nil
+}
+
+// Update applies change to the task with the given ID. When ifMatch is not
+// empty it must equal the task's current ETag (or be "*").
+func (s *Service) Update(id, ifMatch string, change func(task *tasklist.Task) error) (tasklist.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.lookup(id, ifMatch)
+	if err != nil {
+		return tasklist.Task{}, err
+	}
+
+	task := s.tasks.GetTask(index)
+	if err := change(task); err != nil {
+		return tasklist.Task{}, err
+	}
+	task.ID = id
+	if err := validate(*task); err != nil {
+		return tasklist.Task{}, err
+	}
+
+	if err := s.tasks.ReplaceTask(index, task); err != nil {
+		return tasklist.Task{}, err
+	}
+	return *task, nil
+}
+
+func (s *Service) Complete(id, ifMatch string) (tasklist.Task, error) {
+	return s.Update(id, ifMatch, func(task *tasklist.Task) error {
+		task.Done = true
+		return nil
+	})
+}
+
+func (s *Service) Comment(id, ifMatch, comment string) (tasklist.Task, error) {
+	if strings.TrimSpace(comment) == "" {
+		return tasklist.Task{}, fmt.Errorf("%w: comment is empty", ErrInvalidTask)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.lookup(id, ifMatch)
+	if err != nil {
+		return tasklist.Task{}, err
+	}
+	if err := s.tasks.AddComment(index, comment); err != nil {
+		return tasklist.Task{}, err
+	}
+	return *s.tasks.GetTask(index), nil
+}
+
+func (s *Service) Delete(id, ifMatch string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.lookup(id, ifMatch)
+	if err != nil {
+		return err
+	}
+	return s.tasks.RemoveTask(index)
+}
+
+// lookup reloads the list and resolves id, checking the precondition.
+// The caller must hold s.mu.
+func (s *Service) lookup(id, ifMatch string) (int, error) {
+	if err := s.tasks.Reload(); err != nil {
+		return -1, err
+	}
+
+	index := s.tasks.FindTask(id)
+	if index == -1 {
+		return -1, ErrNotFound
+	}
+	if ifMatch != "" && ifMatch != "*" && ifMatch != ETag(*s.tasks.GetTask(index)) {
+		return -1, ErrPreconditionFailed
+	}
+	return index, nil
+}
+
+func (f Filter) matches(task tasklist.Task) bool {
+	if f.Done != nil && task.Done != *f.Done {
+		return false
+	}
+	if f.Tag != "" {
+		found := false
+		for _, tag := range task.Tags {
+			if strings.EqualFold(tag, f.Tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Query != "" && !strings.Contains(strings.ToLower(task.Content), strings.ToLower(f.Query)) {
+		return false
+	}
+	return true
+}
+
+func validate(task tasklist.Task) error {
+	if strings.TrimSpace(task.Content) == "" {
+		return fmt.Errorf("%w: content is required", ErrInvalidTask)
+	}
+	switch task.Priority {
+	case tasklist.PriorityNone, tasklist.PriorityHigh, tasklist.PriorityMedium, tasklist.PriorityLow:
+	default:
+		return fmt.Errorf("%w: priority must be H, M or L", ErrInvalidTask)
+	}
+	return nil
+}
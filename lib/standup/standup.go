@@ -0,0 +1,186 @@
+package standup
+
+import (
+	"fmt"
+	"mytodo/lib/tasklist"
+	"strings"
+	"time"
+)
+
+// Entry is one line of a standup section. Index is the task number, or -1.
+type Entry struct {
+	Index   int
+	Content string
+	Notes   []string
+}
+
+// Issue is a JIRA issue to mention alongside the tasks.
+type Issue struct {
+	Key     string
+	Summary string
+	Status  string
+}
+
+// Report is a "Yesterday / Today / Blockers" standup.
+type Report struct {
+	// Since is the start of the last working day; activity from then on
+	// counts as yesterday's.
+	Since time.Time
+
+	Yesterday []Entry
+	Today     []Entry
+	Blockers  []Entry
+	Jira      []Issue
+}
+
+// LastWorkingDay returns the start of the working day before now's,
+// skipping weekends: on Monday (and over the weekend) that is Friday.
+func LastWorkingDay(now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for {
+		day = day.AddDate(0, 0, -1)
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			return day
+		}
+	}
+}
+
+// Build collects the standup from the task list:
+//   - Yesterday: tasks completed or commented on since the last working day,
+//     archived ones included; those have no task number.
+//   - Today: pending high-priority tasks and tasks due today or overdue.
+//   - Blockers: pending tasks waiting on unfinished tasks.
+//
+// Snoozed tasks are left out of Today and Blockers.
+func Build(tasks, archived []tasklist.Task, now time.Time) Report {
+	report := Report{Since: LastWorkingDay(now)}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	tomorrow := today.AddDate(0, 0, 1)
+
+	byID := map[string]*tasklist.Task{}
+	for i := range tasks {
+		if tasks[i].ID != "" {
+			byID[tasks[i].ID] = &tasks[i]
+		}
+	}
+
+	for i, task := range tasks {
+		if entry, ok := yesterday(task, i, report.Since); ok {
+			report.Yesterday = append(report.Yesterday, entry)
+		}
+
+		if task.Done || task.IsSnoozed(now) {
+			continue
+		}
+
+		var waiting []string
+		for _, id := range task.DependsOn {
+			if dep, ok := byID[id]; ok && !dep.Done {
+				waiting = append(waiting, "waiting on: "+dep.Content)
+			}
+		}
+		if len(waiting) > 0 {
+			report.Blockers = append(report.Blockers, Entry{Index: i, Content: task.Content, Notes: waiting})
+			continue
+		}
+
+		switch {
+		case task.Due != nil && task.Due.Before(today):
+			report.Today = append(report.Today, Entry{Index: i, Content: task.Content, Notes: []string{"overdue since " + task.Due.Format("2006-01-02")}})
+		case task.Due != nil && task.Due.Before(tomorrow):
+			report.Today = append(report.Today, Entry{Index: i, Content: task.Content, Notes: []string{"due today"}})
+		case task.Priority == tasklist.PriorityHigh:
+			report.Today = append(report.Today, Entry{Index: i, Content: task.Content})
+		}
+	}
+
+	for _, task := range archived {
+		if entry, ok := yesterday(task, -1, report.Since); ok {
+			report.Yesterday = append(report.Yesterday, entry)
+		}
+	}
+
+	return report
+}
+
+// yesterday returns the Yesterday entry of a task completed or commented on
+// since the given time.
+func yesterday(task tasklist.Task, index int, since time.Time) (Entry, bool) {
+	var notes []string
+	for _, comment := range task.Comments {
+		if comment.At != nil && !comment.At.Before(since) {
+			notes = append(notes, comment.Text)
+		}
+	}
+	completed := task.Done && task.CompletedAt != nil && !task.CompletedAt.Before(since)
+	if !completed && len(notes) == 0 {
+		return Entry{}, false
+	}
+	content := task.Content
+	if completed {
+		content = "Done: " + content
+	}
+	return Entry{Index: index, Content: content, Notes: notes}, true
+}
+
+// Plain renders the report as plain text.
+func (r Report) Plain() string {
+	var sb strings.Builder
+	section := func(title string, entries []Entry) {
+		sb.WriteString(title + ":\n")
+		if len(entries) == 0 {
+			sb.WriteString("  (nothing)\n")
+		}
+		for _, e := range entries {
+			if e.Index < 0 {
+				sb.WriteString(fmt.Sprintf("  -  %s\n", e.Content))
+			} else {
+				sb.WriteString(fmt.Sprintf("  %d. %s\n", e.Index, e.Content))
+			}
+			for _, note := range e.Notes {
+				sb.WriteString("       - " + note + "\n")
+			}
+		}
+	}
+	section("Yesterday", r.Yesterday)
+	sb.WriteString("\n")
+	section("Today", r.Today)
+	sb.WriteString("\n")
+	section("Blockers", r.Blockers)
+	if len(r.Jira) > 0 {
+		sb.WriteString("\nJIRA:\n")
+		for _, issue := range r.Jira {
+			sb.WriteString(fmt.Sprintf("  %s %s [%s]\n", issue.Key, issue.Summary, issue.Status))
+		}
+	}
+	return sb.String()
+}
+
+// Markdown renders the report as Markdown, ready to paste into chat.
+func (r Report) Markdown() string {
+	var sb strings.Builder
+	section := func(title string, entries []Entry) {
+		sb.WriteString("## " + title + "\n\n")
+		if len(entries) == 0 {
+			sb.WriteString("- Nothing to report\n")
+		}
+		for _, e := range entries {
+			sb.WriteString("- " + e.Content + "\n")
+			for _, note := range e.Notes {
+				sb.WriteString("  - " + note + "\n")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	section("Yesterday", r.Yesterday)
+	section("Today", r.Today)
+	section("Blockers", r.Blockers)
+	if len(r.Jira) > 0 {
+		sb.WriteString("## JIRA\n\n")
+		for _, issue := range r.Jira {
+			sb.WriteString(fmt.Sprintf("- **%s** %s — %s\n", issue.Key, issue.Summary, issue.Status))
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
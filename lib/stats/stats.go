@@ -0,0 +1,195 @@
+package stats
+
+import (
+	"mytodo/lib/tasklist"
+	"sort"
+	"time"
+)
+
+// Day holds the activity of a single calendar day in the window.
+type Day struct {
+	Date      time.Time
+	Created   int
+	Completed int
+	Open      int // open tasks at the end of the day
+}
+
+// Week sums the days of the window that fall in the same Monday-based week.
+type Week struct {
+	Start     time.Time
+	Created   int
+	Completed int
+}
+
+// TagStats is the per-tag breakdown of a Report.
+type TagStats struct {
+	Tag       string
+	Open      int
+	Created   int
+	Completed int
+	MeanTime  time.Duration // mean time to completion, zero if unknown
+}
+
+// Report summarises task activity over a window of days ending today.
+type Report struct {
+	From time.Time
+	To   time.Time
+
+	Days  []Day
+	Weeks []Week
+
+	Open      int
+	Created   int
+	Completed int
+
+	// MeanTime is the mean time from creation to completion of the tasks
+	// completed in the window; Measured is how many of them had both stamps.
+	MeanTime time.Duration
+	Measured int
+
+	// Untracked counts tasks written before timestamps were recorded. They are
+	// treated as created before the window and, if done, completed before it.
+	Untracked int
+
+	ByTag []TagStats
+}
+
+// Compute builds a Report for the given number of days up to and including
+// the day of now.
+func Compute(tasks []tasklist.Task, days int, now time.Time) Report {
+	if days < 1 {
+		days = 1
+	}
+	today := startOfDay(now)
+	from := today.AddDate(0, 0, -(days - 1))
+	end := today.AddDate(0, 0, 1)
+
+	report := Report{From: from, To: today}
+
+	for i := 0; i < days; i++ {
+		date := from.AddDate(0, 0, i)
+		dayEnd := date.AddDate(0, 0, 1)
+		day := Day{Date: date}
+		for _, task := range tasks {
+			if inRange(task.CreatedAt, date, dayEnd) {
+				day.Created++
+			}
+			if inRange(task.CompletedAt, date, dayEnd) {
+				day.Completed++
+			}
+			if openAt(task, dayEnd) {
+				day.Open++
+			}
+		}
+		report.Days = append(report.Days, day)
+		report.Created += day.Created
+		report.Completed += day.Completed
+	}
+	report.Weeks = weeks(report.Days)
+
+	var total time.Duration
+	for _, task := range tasks {
+		if !task.Done {
+			report.Open++
+		}
+		if task.CreatedAt == nil || (task.Done && task.CompletedAt == nil) {
+			report.Untracked++
+		}
+		if d, ok := timeToComplete(task, from, end); ok {
+			total += d
+			report.Measured++
+		}
+	}
+	if report.Measured > 0 {
+		report.MeanTime = total / time.Duration(report.Measured)
+	}
+
+	report.ByTag = byTag(tasks, from, end)
+	return report
+}
+
+func byTag(tasks []tasklist.Task, from, end time.Time) []TagStats {
+	stats := map[string]*TagStats{}
+	totals := map[string]time.Duration{}
+	measured := map[string]int{}
+
+	for _, task := range tasks {
+		for _, tag := range task.Tags {
+			s, ok := stats[tag]
+			if !ok {
+				s = &TagStats{Tag: tag}
+				stats[tag] = s
+			}
+			if !task.Done {
+				s.Open++
+			}
+			if inRange(task.CreatedAt, from, end) {
+				s.Created++
+			}
+			if inRange(task.CompletedAt, from, end) {
+				s.Completed++
+			}
+			if d, ok := timeToComplete(task, from, end); ok {
+				totals[tag] += d
+				measured[tag]++
+			}
+		}
+	}
+
+	result := make([]TagStats, 0, len(stats))
+	for tag, s := range stats {
+		if measured[tag] > 0 {
+			s.MeanTime = totals[tag] / time.Duration(measured[tag])
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Tag < result[j].Tag })
+	return result
+}
+
+// weeks groups consecutive days by the Monday starting their week.
+func weeks(days []Day) []Week {
+	var result []Week
+	for _, day := range days {
+		offset := (int(day.Date.Weekday()) + 6) % 7
+		start := day.Date.AddDate(0, 0, -offset)
+		if len(result) == 0 || !result[len(result)-1].Start.Equal(start) {
+			result = append(result, Week{Start: start})
+		}
+		w := &result[len(result)-1]
+		w.Created += day.Created
+		w.Completed += day.Completed
+	}
+	return result
+}
+
+// openAt reports whether the task existed and was not done at t.
+func openAt(task tasklist.Task, t time.Time) bool {
+	if task.CreatedAt != nil && !task.CreatedAt.Before(t) {
+		return false
+	}
+	if !task.Done {
+		return true
+	}
+	return task.CompletedAt != nil && !task.CompletedAt.Before(t)
+}
+
+func timeToComplete(task tasklist.Task, from, end time.Time) (time.Duration, bool) {
+	if task.CreatedAt == nil || !inRange(task.CompletedAt, from, end) {
+		return 0, false
+	}
+	d := task.CompletedAt.Sub(*task.CreatedAt)
+	if d < 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+func inRange(t *time.Time, from, end time.Time) bool {
+	return t != nil && !t.Before(from) && t.Before(end)
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
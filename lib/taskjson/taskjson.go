@@ -0,0 +1,83 @@
+package taskjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mytodo/lib/tasklist"
+	"sort"
+)
+
+const (
+	// Format identifies the envelope; Version is bumped only for changes that
+	// older readers cannot ignore. New optional task fields do not bump it.
+	// Version 2 writes comments as {"text", "at"} objects instead of strings;
+	// version 1 documents are still read.
+	Format  = "mytodo-tasks"
+	Version = 2
+)
+
+// Envelope is the versioned JSON document used to exchange tasks with
+// plugins and scripts.
+type Envelope struct {
+	Format  string          `json:"format"`
+	Version int             `json:"version"`
+	Tasks   []tasklist.Task `json:"tasks"`
+}
+
+// Encode writes the tasks as an indented Envelope.
+func Encode(w io.Writer, tasks []tasklist.Task) error {
+	if tasks == nil {
+		tasks = []tasklist.Task{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Envelope{Format: Format, Version: Version, Tasks: tasks})
+}
+
+// Decode reads an Envelope. A plain task file ({"tasks": [...]}, without
+// format and version) is accepted too.
+func Decode(r io.Reader) ([]tasklist.Task, error) {
+	tasks, _, err := DecodeFields(r)
+	return tasks, err
+}
+
+// DecodeFields reads an Envelope like Decode, and also returns the names of
+// the fields each task gives, so that updating a stored task leaves the
+// fields a script left out alone. Keys unknown to this version, e.g. fields
+// added by a newer mytodo, are passed on and ignored when updating.
+func DecodeFields(r io.Reader) ([]tasklist.Task, [][]string, error) {
+	var env struct {
+		Format  string            `json:"format"`
+		Version int               `json:"version"`
+		Tasks   []json.RawMessage `json:"tasks"`
+	}
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if env.Format != "" && env.Format != Format {
+		return nil, nil, fmt.Errorf("unexpected format %q, want %q", env.Format, Format)
+	}
+	if env.Version > Version {
+		return nil, nil, fmt.Errorf("format version %d is newer than this mytodo supports (%d)", env.Version, Version)
+	}
+
+	tasks := make([]tasklist.Task, len(env.Tasks))
+	fields := make([][]string, len(env.Tasks))
+	for i, raw := range env.Tasks {
+		var keys map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &keys); err != nil {
+			return nil, nil, fmt.Errorf("task %d: %w", i, err)
+		}
+		if err := json.Unmarshal(raw, &tasks[i]); err != nil {
+			return nil, nil, fmt.Errorf("task %d: %w", i, err)
+		}
+		for key := range keys {
+			if key != "id" {
+				fields[i] = append(fields[i], key)
+			}
+		}
+		sort.Strings(fields[i])
+	}
+	return tasks, fields, nil
+}
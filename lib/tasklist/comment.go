@@ -0,0 +1,34 @@
+package tasklist
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Comment is a note on a task, always written as a {"text", "at"} object.
+// Comments from before they were timestamped have no At, and the plain JSON
+// strings they used to be stored as are still read.
+type Comment struct {
+	Text string     `json:"text"`
+	At   *time.Time `json:"at,omitempty"`
+}
+
+// NewComment returns a comment made now.
+func NewComment(text string) Comment {
+	now := time.Now()
+	return Comment{Text: text, At: &now}
+}
+
+func (c Comment) String() string {
+	return c.Text
+}
+
+func (c *Comment) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = Comment{Text: text}
+		return nil
+	}
+	type plain Comment
+	return json.Unmarshal(data, (*plain)(c))
+}
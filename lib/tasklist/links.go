@@ -0,0 +1,62 @@
+package tasklist
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LinkType tells how a link target should be opened or looked up.
+type LinkType string
+
+const (
+	LinkURL  LinkType = "url"
+	LinkFile LinkType = "file"
+	LinkJira LinkType = "jira"
+	LinkQuip LinkType = "quip"
+)
+
+// Link points a task at something it refers to: a web page, a local file, a
+// JIRA issue or a Quip document.
+type Link struct {
+	Type   LinkType `json:"type"`
+	Target string   `json:"target"`
+	Title  string   `json:"title,omitempty"`
+}
+
+var jiraKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-[0-9]+$`)
+
+// ParseLink classifies target. Anything that is not a URL or a JIRA key is
+// taken to be a file path; callers should make it absolute.
+func ParseLink(target string) Link {
+	target = strings.TrimSpace(target)
+	lower := strings.ToLower(target)
+
+	switch {
+	case strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"):
+		if isQuipURL(lower) {
+			return Link{Type: LinkQuip, Target: target}
+		}
+		return Link{Type: LinkURL, Target: target}
+	case jiraKeyPattern.MatchString(target):
+		return Link{Type: LinkJira, Target: target}
+	case strings.HasPrefix(lower, "file://"):
+		return Link{Type: LinkFile, Target: target[len("file://"):]}
+	default:
+		return Link{Type: LinkFile, Target: target}
+	}
+}
+
+func isQuipURL(lower string) bool {
+	host := strings.TrimPrefix(strings.TrimPrefix(lower, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	return host == "quip.com" || strings.HasSuffix(host, ".quip.com")
+}
+
+// String renders the link for display, e.g. "[jira] ABC-123 (Fix login)".
+func (l Link) String() string {
+	s := "[" + string(l.Type) + "] " + l.Target
+	if l.Title != "" {
+		s += " (" + l.Title + ")"
+	}
+	return s
+}
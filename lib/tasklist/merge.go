@@ -0,0 +1,205 @@
+package tasklist
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Conflict describes a field that was changed to different values on both
+// sides of a merge.
+type Conflict struct {
+	TaskID  string
+	Content string // task content, for display
+	Field   string // JSON name of the field
+	Base    interface{}
+	Local   interface{}
+	Remote  interface{}
+}
+
+// ConflictResolver decides a Conflict. Returning true keeps the local value.
+type ConflictResolver func(c Conflict) (keepLocal bool, err error)
+
+// Merge performs a three-way merge of task lists keyed on task ID.
+//
+// Scalar fields take whichever side changed them; when both sides changed a
+// field to different values the resolver is asked. Slice fields (comments,
+// tags, ...) are merged as sets: additions from both sides are kept and items
+// removed on either side stay removed. The bookkeeping timestamps never
+// conflict: updated_at is the later of the two sides and completed_at follows
+// the merged done. A task deleted on one side is dropped unless the other
+// side edited it.
+//
+// The result keeps the local order, followed by tasks only present remotely.
+func Merge(base, local, remote []Task, resolve ConflictResolver) ([]Task, error) {
+	baseByKey := indexTasks(base)
+	localByKey := indexTasks(local)
+	remoteByKey := indexTasks(remote)
+
+	var keys []string
+	seen := map[string]bool{}
+	for _, list := range [][]Task{local, remote} {
+		for _, task := range list {
+			key := mergeKey(task)
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	var merged []Task
+	for _, key := range keys {
+		b, inBase := baseByKey[key]
+		l, inLocal := localByKey[key]
+		r, inRemote := remoteByKey[key]
+
+		switch {
+		case inLocal && inRemote:
+			task, err := mergeTask(b, l, r, resolve)
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, task)
+		case inLocal:
+			// Deleted remotely: keep it only if it was edited locally since.
+			if !inBase || !reflect.DeepEqual(b, l) {
+				merged = append(merged, l)
+			}
+		case inRemote:
+			if !inBase || !reflect.DeepEqual(b, r) {
+				merged = append(merged, r)
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+func mergeTask(base, local, remote Task, resolve ConflictResolver) (Task, error) {
+	result := local
+
+	bv := reflect.ValueOf(base)
+	lv := reflect.ValueOf(local)
+	rv := reflect.ValueOf(remote)
+	out := reflect.ValueOf(&result).Elem()
+
+	for i := 0; i < out.NumField(); i++ {
+		field := out.Type().Field(i)
+		if !field.IsExported() || field.Name == "ID" || field.Name == "UpdatedAt" || field.Name == "CompletedAt" {
+			continue
+		}
+
+		b, l, r := bv.Field(i), lv.Field(i), rv.Field(i)
+
+		if field.Type.Kind() == reflect.Slice {
+			out.Field(i).Set(mergeSet(b, l, r))
+			continue
+		}
+
+		switch {
+		case reflect.DeepEqual(l.Interface(), r.Interface()):
+			continue
+		case reflect.DeepEqual(l.Interface(), b.Interface()):
+			out.Field(i).Set(r)
+		case reflect.DeepEqual(r.Interface(), b.Interface()):
+			out.Field(i).Set(l)
+		default:
+			if resolve == nil {
+				return Task{}, fmt.Errorf("conflicting changes to %s of task %q", jsonName(field), local.Content)
+			}
+			keepLocal, err := resolve(Conflict{
+				TaskID:  local.ID,
+				Content: local.Content,
+				Field:   jsonName(field),
+				Base:    b.Interface(),
+				Local:   l.Interface(),
+				Remote:  r.Interface(),
+			})
+			if err != nil {
+				return Task{}, err
+			}
+			if !keepLocal {
+				out.Field(i).Set(r)
+			}
+		}
+	}
+
+	result.UpdatedAt = later(local.UpdatedAt, remote.UpdatedAt)
+	switch {
+	case !result.Done:
+		result.CompletedAt = nil
+	case local.Done && local.CompletedAt != nil:
+		result.CompletedAt = local.CompletedAt
+	case remote.Done:
+		result.CompletedAt = remote.CompletedAt
+	}
+
+	return result, nil
+}
+
+func later(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
+
+// mergeSet keeps every item present on either side, except items that were
+// in base and got removed by one of the sides.
+func mergeSet(base, local, remote reflect.Value) reflect.Value {
+	result := reflect.MakeSlice(local.Type(), 0, local.Len()+remote.Len())
+
+	for _, side := range []reflect.Value{local, remote} {
+		for i := 0; i < side.Len(); i++ {
+			item := side.Index(i)
+			if containsValue(result, item) {
+				continue
+			}
+			if containsValue(base, item) && (!containsValue(local, item) || !containsValue(remote, item)) {
+				continue
+			}
+			result = reflect.Append(result, item)
+		}
+	}
+
+	if result.Len() == 0 {
+		return reflect.Zero(local.Type())
+	}
+	return result
+}
+
+func containsValue(slice, item reflect.Value) bool {
+	for i := 0; i < slice.Len(); i++ {
+		if reflect.DeepEqual(slice.Index(i).Interface(), item.Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+func indexTasks(tasks []Task) map[string]Task {
+	m := make(map[string]Task, len(tasks))
+	for _, task := range tasks {
+		m[mergeKey(task)] = task
+	}
+	return m
+}
+
+// mergeKey identifies a task across versions. Files written before tasks had
+// IDs fall back to matching on content.
+func mergeKey(task Task) string {
+	if task.ID != "" {
+		return "id:" + task.ID
+	}
+	return "content:" + task.Content
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
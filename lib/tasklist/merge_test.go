@@ -0,0 +1,192 @@
+package tasklist
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	day := func(d int) *time.Time {
+		at := time.Date(2025, 6, d, 9, 0, 0, 0, time.UTC)
+		return &at
+	}
+	task := func(id, content string, edit func(*Task)) Task {
+		task := Task{ID: id, Content: content}
+		if edit != nil {
+			edit(&task)
+		}
+		return task
+	}
+
+	tests := []struct {
+		name    string
+		base    []Task
+		local   []Task
+		remote  []Task
+		resolve ConflictResolver
+		want    []Task
+		wantErr bool
+	}{
+		{
+			name:   "unchanged",
+			base:   []Task{task("1", "a", nil)},
+			local:  []Task{task("1", "a", nil)},
+			remote: []Task{task("1", "a", nil)},
+			want:   []Task{task("1", "a", nil)},
+		},
+		{
+			name:   "disjoint field edits",
+			base:   []Task{task("1", "a", nil)},
+			local:  []Task{task("1", "a2", func(t *Task) { t.UpdatedAt = day(2) })},
+			remote: []Task{task("1", "a", func(t *Task) { t.Priority = PriorityHigh; t.UpdatedAt = day(3) })},
+			want:   []Task{task("1", "a2", func(t *Task) { t.Priority = PriorityHigh; t.UpdatedAt = day(3) })},
+		},
+		{
+			name:   "same change on both sides",
+			base:   []Task{task("1", "a", nil)},
+			local:  []Task{task("1", "b", nil)},
+			remote: []Task{task("1", "b", nil)},
+			want:   []Task{task("1", "b", nil)},
+		},
+		{
+			name:    "field conflict without resolver",
+			base:    []Task{task("1", "a", nil)},
+			local:   []Task{task("1", "b", nil)},
+			remote:  []Task{task("1", "c", nil)},
+			wantErr: true,
+		},
+		{
+			name:    "field conflict kept local",
+			base:    []Task{task("1", "a", nil)},
+			local:   []Task{task("1", "b", nil)},
+			remote:  []Task{task("1", "c", nil)},
+			resolve: func(Conflict) (bool, error) { return true, nil },
+			want:    []Task{task("1", "b", nil)},
+		},
+		{
+			name:    "field conflict taken from remote",
+			base:    []Task{task("1", "a", nil)},
+			local:   []Task{task("1", "b", nil)},
+			remote:  []Task{task("1", "c", nil)},
+			resolve: func(Conflict) (bool, error) { return false, nil },
+			want:    []Task{task("1", "c", nil)},
+		},
+		{
+			name:    "resolver error",
+			base:    []Task{task("1", "a", nil)},
+			local:   []Task{task("1", "b", nil)},
+			remote:  []Task{task("1", "c", nil)},
+			resolve: func(Conflict) (bool, error) { return false, errors.New("no answer") },
+			wantErr: true,
+		},
+		{
+			name:   "timestamps never conflict",
+			base:   []Task{task("1", "a", nil)},
+			local:  []Task{task("1", "a", func(t *Task) { t.Done = true; t.CompletedAt = day(2); t.UpdatedAt = day(2) })},
+			remote: []Task{task("1", "a", func(t *Task) { t.Done = true; t.CompletedAt = day(4); t.UpdatedAt = day(4) })},
+			want:   []Task{task("1", "a", func(t *Task) { t.Done = true; t.CompletedAt = day(2); t.UpdatedAt = day(4) })},
+		},
+		{
+			name:   "completed_at follows merged done",
+			base:   []Task{task("1", "a", func(t *Task) { t.Done = true; t.CompletedAt = day(1) })},
+			local:  []Task{task("1", "a", func(t *Task) { t.Done = false; t.UpdatedAt = day(2) })},
+			remote: []Task{task("1", "a2", func(t *Task) { t.Done = true; t.CompletedAt = day(1); t.UpdatedAt = day(3) })},
+			want:   []Task{task("1", "a2", func(t *Task) { t.UpdatedAt = day(3) })},
+		},
+		{
+			name:   "set union of additions",
+			base:   []Task{task("1", "a", func(t *Task) { t.Tags = []string{"x"} })},
+			local:  []Task{task("1", "a", func(t *Task) { t.Tags = []string{"x", "l"} })},
+			remote: []Task{task("1", "a", func(t *Task) { t.Tags = []string{"x", "r"} })},
+			want:   []Task{task("1", "a", func(t *Task) { t.Tags = []string{"x", "l", "r"} })},
+		},
+		{
+			name:   "set removal on one side wins",
+			base:   []Task{task("1", "a", func(t *Task) { t.Tags = []string{"x", "y"} })},
+			local:  []Task{task("1", "a", func(t *Task) { t.Tags = []string{"y"} })},
+			remote: []Task{task("1", "a", func(t *Task) { t.Tags = []string{"x", "y", "z"} })},
+			want:   []Task{task("1", "a", func(t *Task) { t.Tags = []string{"y", "z"} })},
+		},
+		{
+			name:   "set emptied on one side",
+			base:   []Task{task("1", "a", func(t *Task) { t.Comments = []Comment{{Text: "c"}} })},
+			local:  []Task{task("1", "a", nil)},
+			remote: []Task{task("1", "a", func(t *Task) { t.Comments = []Comment{{Text: "c"}} })},
+			want:   []Task{task("1", "a", nil)},
+		},
+		{
+			name:   "deleted remotely, unchanged locally",
+			base:   []Task{task("1", "a", nil), task("2", "b", nil)},
+			local:  []Task{task("1", "a", nil), task("2", "b", nil)},
+			remote: []Task{task("2", "b", nil)},
+			want:   []Task{task("2", "b", nil)},
+		},
+		{
+			name:   "deleted remotely, edited locally",
+			base:   []Task{task("1", "a", nil)},
+			local:  []Task{task("1", "a2", nil)},
+			remote: nil,
+			want:   []Task{task("1", "a2", nil)},
+		},
+		{
+			name:   "deleted locally, edited remotely",
+			base:   []Task{task("1", "a", nil)},
+			local:  nil,
+			remote: []Task{task("1", "a", func(t *Task) { t.Done = true })},
+			want:   []Task{task("1", "a", func(t *Task) { t.Done = true })},
+		},
+		{
+			name:   "added on both sides, local order first",
+			base:   []Task{task("1", "a", nil)},
+			local:  []Task{task("1", "a", nil), task("2", "l", nil)},
+			remote: []Task{task("3", "r", nil), task("1", "a", nil)},
+			want:   []Task{task("1", "a", nil), task("2", "l", nil), task("3", "r", nil)},
+		},
+		{
+			name:   "tasks without IDs match on content",
+			base:   []Task{{Content: "a"}},
+			local:  []Task{{Content: "a", Priority: PriorityLow}},
+			remote: []Task{{Content: "a", Tags: []string{"t"}}},
+			want:   []Task{{Content: "a", Priority: PriorityLow, Tags: []string{"t"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge(tt.base, tt.local, tt.remote, tt.resolve)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Merge() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() =\n  %+v\nwant\n  %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeConflictDetails(t *testing.T) {
+	base := []Task{{ID: "1", Content: "a", Priority: PriorityLow}}
+	local := []Task{{ID: "1", Content: "a", Priority: PriorityMedium}}
+	remote := []Task{{ID: "1", Content: "a", Priority: PriorityHigh}}
+
+	var got []Conflict
+	_, err := Merge(base, local, remote, func(c Conflict) (bool, error) {
+		got = append(got, c)
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	want := []Conflict{{TaskID: "1", Content: "a", Field: "priority", Base: PriorityLow, Local: PriorityMedium, Remote: PriorityHigh}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("conflicts = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package tasklist
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TaskSchema is the JSON Schema of a Task as it is stored in the task file.
+// Files written before comments were timestamped may still hold them as plain
+// strings; the schema only describes the current form.
+var TaskSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"id":       stringSchema("Unique ID, assigned by mytodo when empty"),
+		"content":  stringSchema("What needs to be done"),
+		"done":     map[string]interface{}{"type": "boolean"},
+		"comments": arraySchema(commentSchema),
+		"due":      dateTimeSchema("When the task is due"),
+		"priority": map[string]interface{}{
+			"type": "string",
+			"enum": []string{string(PriorityNone), string(PriorityHigh), string(PriorityMedium), string(PriorityLow)},
+		},
+		"tags":          arraySchema(map[string]interface{}{"type": "string"}),
+		"recurrence":    stringSchema("RFC 5545 RRULE value, e.g. FREQ=WEEKLY;BYDAY=MO"),
+		"depends":       arraySchema(stringSchema("ID of a task that must be done first")),
+		"snoozed_until": dateTimeSchema("Hidden from the list until then"),
+		"parent":        stringSchema("ID of the task this is a subtask of"),
+		"links":         arraySchema(linkSchema),
+		"created_at":    dateTimeSchema(""),
+		"completed_at":  dateTimeSchema(""),
+		"updated_at":    dateTimeSchema(""),
+	},
+	"required":             []string{"content", "done"},
+	"additionalProperties": false,
+}
+
+var commentSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"text": map[string]interface{}{"type": "string"},
+		"at":   dateTimeSchema(""),
+	},
+	"required":             []string{"text"},
+	"additionalProperties": false,
+}
+
+var linkSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"type": map[string]interface{}{
+			"type": "string",
+			"enum": []string{string(LinkURL), string(LinkFile), string(LinkJira), string(LinkQuip)},
+		},
+		"target": map[string]interface{}{"type": "string"},
+		"title":  map[string]interface{}{"type": "string"},
+	},
+	"required":             []string{"type", "target"},
+	"additionalProperties": false,
+}
+
+func stringSchema(description string) map[string]interface{} {
+	s := map[string]interface{}{"type": "string"}
+	if description != "" {
+		s["description"] = description
+	}
+	return s
+}
+
+func dateTimeSchema(description string) map[string]interface{} {
+	s := stringSchema(description)
+	s["format"] = "date-time"
+	return s
+}
+
+func arraySchema(items map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": items}
+}
+
+// Validate checks what TaskSchema requires beyond the JSON types, which
+// decoding into a Task already enforces.
+func (task *Task) Validate() error {
+	var problems []string
+	if strings.TrimSpace(task.Content) == "" {
+		problems = append(problems, "content is required")
+	}
+	switch task.Priority {
+	case PriorityNone, PriorityHigh, PriorityMedium, PriorityLow:
+	default:
+		problems = append(problems, fmt.Sprintf("priority %q must be H, M or L", task.Priority))
+	}
+	if rule := strings.ToUpper(task.Recurrence); rule != "" && !strings.HasPrefix(rule, "FREQ=") && !strings.Contains(rule, ";FREQ=") {
+		problems = append(problems, fmt.Sprintf("recurrence %q is not an RRULE value with FREQ", task.Recurrence))
+	}
+	for i, tag := range task.Tags {
+		if strings.TrimSpace(tag) == "" {
+			problems = append(problems, fmt.Sprintf("tags[%d] is empty", i))
+		}
+	}
+	for i, id := range task.DependsOn {
+		if strings.TrimSpace(id) == "" {
+			problems = append(problems, fmt.Sprintf("depends[%d] is empty", i))
+		}
+	}
+	for i, comment := range task.Comments {
+		if strings.TrimSpace(comment.Text) == "" {
+			problems = append(problems, fmt.Sprintf("comments[%d] has no text", i))
+		}
+	}
+	for i, link := range task.Links {
+		switch link.Type {
+		case LinkURL, LinkFile, LinkJira, LinkQuip:
+		default:
+			problems = append(problems, fmt.Sprintf("links[%d] type %q must be url, file, jira or quip", i, link.Type))
+		}
+		if strings.TrimSpace(link.Target) == "" {
+			problems = append(problems, fmt.Sprintf("links[%d] has no target", i))
+		}
+	}
+	if task.CompletedAt != nil && !task.Done {
+		problems = append(problems, "completed_at is set on a task that is not done")
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -1,20 +1,98 @@
 package tasklist
 
 import (
+	"bytes"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidIndex  = errors.New("invalid task number")
+	ErrUnknownFormat = errors.New("unrecognized task file format (is it encrypted?)")
+	ErrRejected      = errors.New("change rejected by hook")
 )
 
 type TaskList struct {
 	Tasks    []Task `json:"tasks"`
 	filePath string `json:"-"`
+	codec    Codec  `json:"-"`
+	hook     Hook   `json:"-"`
+}
+
+// Codec transforms the task file content on its way to and from disk, e.g.
+// to encrypt it at rest.
+type Codec interface {
+	Encode(plain []byte) ([]byte, error)
+	Decode(stored []byte) ([]byte, error)
+}
+
+// Event names a change to a single task.
+type Event string
+
+const (
+	EventAdd     Event = "on-add"
+	EventDone    Event = "on-done"
+	EventEdit    Event = "on-edit"
+	EventComment Event = "on-comment"
+	EventRemove  Event = "on-remove"
+)
+
+// Hook is told about every change made through the TaskList mutators before
+// it is saved, with the task as it will be stored (or, for removals, as it
+// was). Returning an error vetoes the change; hooks wrap ErrRejected when the
+// veto is deliberate rather than a failure to run.
+type Hook interface {
+	Run(event Event, task *Task) error
 }
 
+// Priority follows the H/M/L convention used by most task managers.
+type Priority string
+
+const (
+	PriorityNone   Priority = ""
+	PriorityHigh   Priority = "H"
+	PriorityMedium Priority = "M"
+	PriorityLow    Priority = "L"
+)
+
 type Task struct {
-	Content  string   `json:"content"`
-	Done     bool     `json:"done"`
-	Comments []string `json:"comments,omitempty"`
+	ID         string     `json:"id,omitempty"`
+	Content    string     `json:"content"`
+	Done       bool       `json:"done"`
+	Comments   []Comment  `json:"comments,omitempty"`
+	Due        *time.Time `json:"due,omitempty"`
+	Priority   Priority   `json:"priority,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+	Recurrence string     `json:"recurrence,omitempty"`    // RFC 5545 RRULE value, e.g. FREQ=WEEKLY;BYDAY=MO
+	DependsOn  []string   `json:"depends,omitempty"`       // IDs of tasks that must be done first
+	Snoozed    *time.Time `json:"snoozed_until,omitempty"` // hidden from the list until then
+	ParentID   string     `json:"parent,omitempty"`        // ID of the task this is a subtask of
+	Links      []Link     `json:"links,omitempty"`
+
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// IsSnoozed reports whether the task is still hidden at the given time.
+func (task *Task) IsSnoozed(now time.Time) bool {
+	return task.Snoozed != nil && now.Before(*task.Snoozed)
+}
+
+// LastTouched returns when the task was last changed or, failing that,
+// created. It is nil for tasks from before timestamps were recorded.
+func (task *Task) LastTouched() *time.Time {
+	if task.UpdatedAt != nil {
+		return task.UpdatedAt
+	}
+	return task.CreatedAt
 }
 
 func NewTaskList(filepath string) *TaskList {
@@ -24,13 +102,50 @@ func NewTaskList(filepath string) *TaskList {
 	}
 }
 
-func (t *TaskList) Save() {
+// NewTaskID returns a random RFC 4122 version 4 UUID.
+func NewTaskID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate task ID: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// SetCodec changes how the file is stored from the next Save on. A nil codec
+// stores plain JSON.
+func (t *TaskList) SetCodec(c Codec) {
+	t.codec = c
+}
+
+// SetHook installs h to be run by AddTask, ReplaceTask, AddComment and
+// RemoveTask. A nil hook disables hooks.
+func (t *TaskList) SetHook(h Hook) {
+	t.hook = h
+}
+
+func (t *TaskList) runHook(event Event, task *Task) error {
+	if t.hook == nil {
+		return nil
+	}
+	return t.hook.Run(event, task)
+}
+
+func (t *TaskList) Save() error {
 	content, err := json.MarshalIndent(t, "", "  ")
 	if err != nil {
-		fmt.Println("Error marshalling tasks:", err)
-		return
+		return fmt.Errorf("marshalling tasks: %w", err)
+	}
+	if t.codec != nil {
+		if content, err = t.codec.Encode(content); err != nil {
+			return fmt.Errorf("encoding tasks: %w", err)
+		}
+	}
+	if err := writeFileAtomic(t.filePath, content); err != nil {
+		return fmt.Errorf("writing tasks: %w", err)
 	}
-	os.WriteFile(t.filePath, content, 0644)
+	return nil
 }
 
 func (t *TaskList) Load() error {
@@ -42,21 +157,292 @@ func (t *TaskList) Load() error {
 		fmt.Println("Error reading tasks:", err)
 		return err
 	}
-	return json.Unmarshal(content, &t)
+	tasks, err := t.ParseContent(content)
+	if err != nil {
+		return err
+	}
+	if tasks == nil {
+		tasks = []Task{}
+	}
+	t.Tasks = tasks
+
+	// Files written before tasks carried IDs get them assigned once and
+	// persisted, so exports keep producing the same identifiers.
+	assigned := false
+	for i := range t.Tasks {
+		if t.Tasks[i].ID == "" {
+			t.Tasks[i].ID = NewTaskID()
+			assigned = true
+		}
+	}
+	if assigned {
+		return t.Save()
+	}
+	return nil
 }
 
-func (t *TaskList) AddTask(task *Task) {
+// ParseContent decodes stored task file content with the list's codec.
+func (t *TaskList) ParseContent(content []byte) ([]Task, error) {
+	if t.codec != nil {
+		plain, err := t.codec.Decode(content)
+		if err != nil {
+			return nil, err
+		}
+		content = plain
+	}
+	return ParseTasks(content)
+}
+
+// ParseTasks decodes plain task file content.
+func ParseTasks(content []byte) ([]Task, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, err
+	}
+	// Refuse anything that is not a task file, so that a later Save does not
+	// overwrite it with an empty list.
+	raw, ok := fields["tasks"]
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+
+	var tasks []Task
+	if err := json.Unmarshal(raw, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// writeFileAtomic replaces path through a temporary file so a crash never
+// leaves a half-written task file. The file is only readable by its owner.
+func writeFileAtomic(path string, content []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Reload discards the in-memory tasks and reads the file again.
+func (t *TaskList) Reload() error {
+	t.Tasks = []Task{}
+	return t.Load()
+}
+
+// FilePath returns the location of the backing task file.
+func (t *TaskList) FilePath() string {
+	return t.filePath
+}
+
+// SetTasks replaces the whole list, e.g. with the result of a merge.
+func (t *TaskList) SetTasks(tasks []Task) error {
+	t.Tasks = append([]Task{}, tasks...)
+	return t.Save()
+}
+
+func (t *TaskList) AddTask(task *Task) error {
+	if task.ID == "" {
+		task.ID = NewTaskID()
+	}
+	now := time.Now()
+	if task.CreatedAt == nil {
+		task.CreatedAt = &now
+	}
+	if task.Done && task.CompletedAt == nil {
+		task.CompletedAt = &now
+	}
+	if err := t.runHook(EventAdd, task); err != nil {
+		return err
+	}
 	t.Tasks = append(t.Tasks, *task)
-	t.Save()
+	return t.Save()
+}
+
+// UpsertTask updates the task sharing the same ID with the given fields of
+// task, named by their JSON names, or appends task when no such task exists.
+// The other fields of the stored task are kept, so that an import does not
+// clear what its format cannot carry; stored comments that come back without
+// a timestamp keep theirs. Names this version has no field for, as a newer
+// writer may send, are ignored. It reports whether the task was newly added.
+func (t *TaskList) UpsertTask(task *Task, fields []string) (bool, error) {
+	index := t.FindTask(task.ID)
+	if index == -1 {
+		return true, t.AddTask(task)
+	}
+
+	updated := t.Tasks[index]
+	copyFields(&updated, task, fields)
+	return false, t.ReplaceTask(index, &updated)
+}
+
+// copyFields sets the named fields of dst to those of src, skipping names
+// that are not task fields.
+func copyFields(dst, src *Task, fields []string) {
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src).Elem()
+	for _, name := range fields {
+		if name == "comments" {
+			dst.Comments = keepCommentTimes(dst.Comments, src.Comments)
+			continue
+		}
+		for i := 0; i < dv.NumField(); i++ {
+			field := dv.Type().Field(i)
+			if field.IsExported() && jsonName(field) == name {
+				dv.Field(i).Set(sv.Field(i))
+				break
+			}
+		}
+	}
+}
+
+// keepCommentTimes returns the incoming comments, with the timestamp of a
+// stored comment of the same text on those that have none.
+func keepCommentTimes(stored, incoming []Comment) []Comment {
+	used := make([]bool, len(stored))
+	result := make([]Comment, 0, len(incoming))
+	for _, comment := range incoming {
+		if comment.At == nil {
+			for i, s := range stored {
+				if !used[i] && s.Text == comment.Text {
+					comment, used[i] = s, true
+					break
+				}
+			}
+		}
+		result = append(result, comment)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// FindTask returns the index of the task with the given ID, or -1.
+func (t *TaskList) FindTask(id string) int {
+	if id == "" {
+		return -1
+	}
+	for i := range t.Tasks {
+		if t.Tasks[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Subtasks returns the IDs of the subtasks of the task with the given ID,
+// theirs included, parents before their subtasks.
+func (t *TaskList) Subtasks(id string) []string {
+	var ids []string
+	seen := map[string]bool{id: true}
+	var walk func(parent string)
+	walk = func(parent string) {
+		for _, task := range t.Tasks {
+			if task.ParentID == parent && task.ID != "" && !seen[task.ID] {
+				seen[task.ID] = true
+				ids = append(ids, task.ID)
+				walk(task.ID)
+			}
+		}
+	}
+	walk(id)
+	return ids
+}
+
+// ArchivePath returns the file archived tasks are moved to, next to the task
+// file: ~/.mytodo.json archives to ~/.mytodo.archive.json.
+func (t *TaskList) ArchivePath() string {
+	return strings.TrimSuffix(t.filePath, ".json") + ".archive.json"
 }
 
-func (t *TaskList) RemoveTask(index int) {
+// LoadArchive reads the list's archive with the list's codec. A missing
+// archive file yields an empty list.
+func (t *TaskList) LoadArchive() (*TaskList, error) {
+	archive := NewTaskList(t.ArchivePath())
+	archive.SetCodec(t.codec)
+	if err := archive.Load(); err != nil {
+		return nil, fmt.Errorf("reading archive: %w", err)
+	}
+	return archive, nil
+}
+
+// Archive moves the tasks at the given indexes to the archive file, stored
+// with the same codec as the list. The archive is written first, so a failure
+// can at worst leave a task in both files. Archiving runs no hooks: the tasks
+// are kept, just out of the way. Subtasks left behind become top-level tasks.
+func (t *TaskList) Archive(indexes []int) error {
+	selected := map[int]bool{}
+	for _, index := range indexes {
+		if index < 0 || index >= len(t.Tasks) {
+			return ErrInvalidIndex
+		}
+		selected[index] = true
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	archive, err := t.LoadArchive()
+	if err != nil {
+		return err
+	}
+	archived := map[string]bool{}
+	kept := make([]Task, 0, len(t.Tasks)-len(selected))
+	for i, task := range t.Tasks {
+		if selected[i] {
+			archive.Tasks = append(archive.Tasks, task)
+			archived[task.ID] = true
+		} else {
+			kept = append(kept, task)
+		}
+	}
+	now := time.Now()
+	for i := range kept {
+		if kept[i].ParentID != "" && archived[kept[i].ParentID] {
+			kept[i].ParentID = ""
+			kept[i].UpdatedAt = &now
+		}
+	}
+	if err := archive.Save(); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+
+	t.Tasks = kept
+	return t.Save()
+}
+
+func (t *TaskList) RemoveTask(index int) error {
 	if index < 0 || index >= len(t.Tasks) {
-		return
+		return ErrInvalidIndex
 	}
 
+	removed := t.Tasks[index]
+	if err := t.runHook(EventRemove, &removed); err != nil {
+		return err
+	}
 	t.Tasks = append(t.Tasks[:index], t.Tasks[index+1:]...)
-	t.Save()
+	return t.Save()
 }
 
 func (t *TaskList) GetTask(index int) *Task {
@@ -68,13 +454,31 @@ func (t *TaskList) GetTask(index int) *Task {
 	return &copy
 }
 
-func (t *TaskList) ReplaceTask(index int, newTask *Task) {
+func (t *TaskList) ReplaceTask(index int, newTask *Task) error {
 	if index < 0 || index >= len(t.Tasks) {
-		return
+		return ErrInvalidIndex
+	}
+
+	// Keep the completion timestamp in step with the done flag. Tasks that were
+	// already done before timestamps existed are left without one.
+	now := time.Now()
+	if !newTask.Done {
+		newTask.CompletedAt = nil
+	} else if newTask.CompletedAt == nil && !t.Tasks[index].Done {
+		newTask.CompletedAt = &now
+	}
+	newTask.UpdatedAt = &now
+
+	event := EventEdit
+	if newTask.Done && !t.Tasks[index].Done {
+		event = EventDone
+	}
+	if err := t.runHook(event, newTask); err != nil {
+		return err
 	}
 
 	t.Tasks[index] = *newTask
-	t.Save()
+	return t.Save()
 }
 
 func (t *TaskList) NumberOfTasks() int {
@@ -92,19 +496,26 @@ func (t *TaskList) GetAllTasks() []Task {
 	return copy
 }
 
-func (t *TaskList) AddComment(index int, comment string) {
+func (t *TaskList) AddComment(index int, comment string) error {
 	if index < 0 || index >= len(t.Tasks) {
-		return
+		return ErrInvalidIndex
+	}
+
+	task := t.Tasks[index]
+	task.Comments = append(append([]Comment{}, task.Comments...), NewComment(comment))
+	task.UpdatedAt = task.Comments[len(task.Comments)-1].At
+	if err := t.runHook(EventComment, &task); err != nil {
+		return err
 	}
 
-	t.Tasks[index].Comments = append(t.Tasks[index].Comments, comment)
-	t.Save()
+	t.Tasks[index] = task
+	return t.Save()
 }
-func (t *TaskList) GetComments(index int) []string {
+func (t *TaskList) GetComments(index int) []Comment {
 	if index < 0 || index >= len(t.Tasks) {
 		return nil
 	}
-	copies := make([]string, 0, len(t.Tasks[index].Comments))
+	copies := make([]Comment, 0, len(t.Tasks[index].Comments))
 	copies = append(copies, t.Tasks[index].Comments[0:]...)
 
 	return copies
@@ -0,0 +1,90 @@
+package tasklist
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUpsertTask(t *testing.T) {
+	at := time.Date(2025, 6, 2, 9, 15, 0, 0, time.UTC)
+	stored := Task{
+		ID:        "1",
+		Content:   "Buy groceries",
+		Priority:  PriorityLow,
+		Tags:      []string{"home"},
+		DependsOn: []string{"2"},
+		Links:     []Link{{Type: LinkURL, Target: "https://example.com"}},
+		Comments:  []Comment{{Text: "milk", At: &at}},
+	}
+
+	tests := []struct {
+		name      string
+		task      Task
+		fields    []string
+		wantAdded bool
+		want      Task
+	}{
+		{
+			name:   "only the given fields change",
+			task:   Task{ID: "1", Content: "ignored", Priority: PriorityHigh},
+			fields: []string{"priority"},
+			want: func() Task {
+				task := stored
+				task.Priority = PriorityHigh
+				return task
+			}(),
+		},
+		{
+			name:   "fields the format does not carry are kept",
+			task:   Task{ID: "1", Content: "Buy food", Comments: []Comment{{Text: "milk"}, {Text: "eggs"}}},
+			fields: []string{"content", "done", "priority", "tags", "comments"},
+			want: func() Task {
+				task := stored
+				task.Content, task.Priority, task.Tags = "Buy food", PriorityNone, nil
+				task.Comments = []Comment{{Text: "milk", At: &at}, {Text: "eggs"}}
+				return task
+			}(),
+		},
+		{
+			name:   "unknown field names are ignored",
+			task:   Task{ID: "1", Priority: PriorityMedium},
+			fields: []string{"priority", "added_in_a_later_version"},
+			want: func() Task {
+				task := stored
+				task.Priority = PriorityMedium
+				return task
+			}(),
+		},
+		{
+			name:      "new tasks are added whole",
+			task:      Task{ID: "9", Content: "New"},
+			fields:    []string{"content"},
+			wantAdded: true,
+			want:      Task{ID: "9", Content: "New"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := NewTaskList(filepath.Join(t.TempDir(), "tasks.json"))
+			list.Tasks = []Task{stored}
+
+			task := tt.task
+			added, err := list.UpsertTask(&task, tt.fields)
+			if err != nil {
+				t.Fatalf("UpsertTask() error = %v", err)
+			}
+			if added != tt.wantAdded {
+				t.Errorf("UpsertTask() added = %v, want %v", added, tt.wantAdded)
+			}
+
+			got := list.Tasks[list.FindTask(tt.want.ID)]
+			got.CreatedAt, got.UpdatedAt = nil, nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stored task =\n  %+v\nwant\n  %+v", got, tt.want)
+			}
+		})
+	}
+}
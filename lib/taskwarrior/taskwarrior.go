@@ -0,0 +1,274 @@
+package taskwarrior
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mytodo/lib/tasklist"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Taskwarrior stores dates in ISO 8601 basic format, always in UTC.
+const dateLayout = "20060102T150405Z"
+
+// Fields that are either mapped onto tasklist.Task or are derived values
+// Taskwarrior recomputes itself, so they never show up in the report.
+var handledFields = map[string]bool{
+	"uuid":        true,
+	"description": true,
+	"status":      true,
+	"due":         true,
+	"priority":    true,
+	"tags":        true,
+	"annotations": true,
+	"depends":     true,
+	"entry":       true,
+	"end":         true,
+	"id":          true, // working-set number, changes on every `task` run
+	"urgency":     true, // computed
+}
+
+// Fields are the task fields, by JSON name, that a Taskwarrior task carries.
+// Importing into an existing task only replaces these.
+var Fields = []string{"content", "done", "due", "created_at", "completed_at", "priority", "tags", "comments", "depends"}
+
+// Report describes what could not be carried over from a `task export`.
+type Report struct {
+	// UnmappedFields counts, per Taskwarrior attribute, how many tasks
+	// carried a value that has no equivalent in tasklist.Task.
+	UnmappedFields map[string]int
+	// Skipped counts tasks that were not imported, keyed by status.
+	Skipped map[string]int
+}
+
+// String renders the report for terminal output.
+func (r *Report) String() string {
+	var sb strings.Builder
+
+	if len(r.Skipped) > 0 {
+		sb.WriteString("Skipped tasks:\n")
+		for _, status := range sortedKeys(r.Skipped) {
+			sb.WriteString(fmt.Sprintf("  - %s: %d\n", status, r.Skipped[status]))
+		}
+	}
+
+	if len(r.UnmappedFields) > 0 {
+		sb.WriteString("Fields that could not be mapped:\n")
+		for _, field := range sortedKeys(r.UnmappedFields) {
+			sb.WriteString(fmt.Sprintf("  - %s (%d task(s))\n", field, r.UnmappedFields[field]))
+		}
+	}
+
+	return sb.String()
+}
+
+type annotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// Decode reads the output of `task export`, which is either a JSON array
+// (Taskwarrior 2.6+) or one JSON object per line (older releases).
+func Decode(r io.Reader) ([]tasklist.Task, *Report, error) {
+	raw, err := readObjects(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &Report{
+		UnmappedFields: map[string]int{},
+		Skipped:        map[string]int{},
+	}
+
+	var tasks []tasklist.Task
+	for i, obj := range raw {
+		task, skip, err := convert(obj, report)
+		if err != nil {
+			return nil, nil, fmt.Errorf("task %d: %w", i+1, err)
+		}
+		if skip {
+			continue
+		}
+		tasks = append(tasks, *task)
+	}
+
+	return tasks, report, nil
+}
+
+func readObjects(r io.Reader) ([]map[string]json.RawMessage, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading export: %w", err)
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var objects []map[string]json.RawMessage
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &objects); err != nil {
+			return nil, fmt.Errorf("parsing export: %w", err)
+		}
+		return objects, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ",")
+		if text == "" {
+			continue
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(text), &obj); err != nil {
+			return nil, fmt.Errorf("parsing export line %d: %w", line, err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, scanner.Err()
+}
+
+func convert(obj map[string]json.RawMessage, report *Report) (*tasklist.Task, bool, error) {
+	var status string
+	if err := decodeField(obj, "status", &status); err != nil {
+		return nil, false, err
+	}
+
+	switch status {
+	case "deleted", "recurring":
+		// Deleted tasks are gone for the user; recurring entries are templates
+		// whose pending instances are exported separately.
+		report.Skipped[status]++
+		return nil, true, nil
+	}
+
+	task := &tasklist.Task{Done: status == "completed"}
+	var err error
+
+	if err = decodeField(obj, "uuid", &task.ID); err != nil {
+		return nil, false, err
+	}
+	if err := decodeField(obj, "description", &task.Content); err != nil {
+		return nil, false, err
+	}
+
+	if task.Due, err = decodeDate(obj, "due"); err != nil {
+		return nil, false, err
+	}
+	if task.CreatedAt, err = decodeDate(obj, "entry"); err != nil {
+		return nil, false, err
+	}
+	if task.Done {
+		// "end" is also set on deleted tasks, which are skipped above.
+		if task.CompletedAt, err = decodeDate(obj, "end"); err != nil {
+			return nil, false, err
+		}
+	}
+
+	var priority string
+	if err := decodeField(obj, "priority", &priority); err != nil {
+		return nil, false, err
+	}
+	switch tasklist.Priority(priority) {
+	case tasklist.PriorityHigh, tasklist.PriorityMedium, tasklist.PriorityLow:
+		task.Priority = tasklist.Priority(priority)
+	case tasklist.PriorityNone:
+	default:
+		report.UnmappedFields["priority"]++
+	}
+
+	if err := decodeField(obj, "tags", &task.Tags); err != nil {
+		return nil, false, err
+	}
+
+	var annotations []annotation
+	if err := decodeField(obj, "annotations", &annotations); err != nil {
+		return nil, false, err
+	}
+	for _, a := range annotations {
+		comment := tasklist.Comment{Text: a.Description}
+		if t, err := time.Parse(dateLayout, a.Entry); err == nil {
+			comment.At = &t
+		}
+		task.Comments = append(task.Comments, comment)
+	}
+
+	depends, err := decodeDepends(obj["depends"])
+	if err != nil {
+		return nil, false, err
+	}
+	task.DependsOn = depends
+
+	for field := range obj {
+		if !handledFields[field] {
+			report.UnmappedFields[field]++
+		}
+	}
+
+	return task, false, nil
+}
+
+func decodeDate(obj map[string]json.RawMessage, field string) (*time.Time, error) {
+	var value string
+	if err := decodeField(obj, field, &value); err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s date %q: %w", field, value, err)
+	}
+	return &t, nil
+}
+
+func decodeField(obj map[string]json.RawMessage, field string, dst interface{}) error {
+	raw, ok := obj[field]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return nil
+}
+
+// decodeDepends accepts both the comma-separated string written by
+// Taskwarrior 2.x and the array written by 3.x.
+func decodeDepends(raw json.RawMessage) ([]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+
+	var joined string
+	if err := json.Unmarshal(raw, &joined); err != nil {
+		return nil, fmt.Errorf("invalid depends: %w", err)
+	}
+	for _, uuid := range strings.Split(joined, ",") {
+		if uuid = strings.TrimSpace(uuid); uuid != "" {
+			list = append(list, uuid)
+		}
+	}
+	return list, nil
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
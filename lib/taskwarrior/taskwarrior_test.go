@@ -0,0 +1,111 @@
+package taskwarrior
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mytodo/lib/tasklist"
+)
+
+func TestDecode(t *testing.T) {
+	at := func(s string) *time.Time {
+		parsed, err := time.Parse(dateLayout, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &parsed
+	}
+
+	tests := []struct {
+		name       string
+		input      string
+		want       []tasklist.Task
+		wantReport Report
+		wantErr    bool
+	}{
+		{
+			name:       "empty export",
+			input:      "  \n",
+			wantReport: Report{UnmappedFields: map[string]int{}, Skipped: map[string]int{}},
+		},
+		{
+			name: "JSON array with every mapped field",
+			input: `[{"id":3,"uuid":"a","description":"Write report","status":"completed","entry":"20250601T080000Z",
+				"end":"20250603T173000Z","due":"20250604T120000Z","priority":"H","tags":["work"],"urgency":4.2,
+				"annotations":[{"entry":"20250602T090000Z","description":"draft sent"}],"depends":["b","c"]}]`,
+			want: []tasklist.Task{{
+				ID:          "a",
+				Content:     "Write report",
+				Done:        true,
+				CreatedAt:   at("20250601T080000Z"),
+				CompletedAt: at("20250603T173000Z"),
+				Due:         at("20250604T120000Z"),
+				Priority:    tasklist.PriorityHigh,
+				Tags:        []string{"work"},
+				Comments:    []tasklist.Comment{{Text: "draft sent", At: at("20250602T090000Z")}},
+				DependsOn:   []string{"b", "c"},
+			}},
+			wantReport: Report{UnmappedFields: map[string]int{}, Skipped: map[string]int{}},
+		},
+		{
+			name: "one object per line, depends as a string",
+			input: `{"uuid":"a","description":"one","status":"pending","depends":"b, c"},
+{"uuid":"b","description":"two","status":"pending"}`,
+			want: []tasklist.Task{
+				{ID: "a", Content: "one", DependsOn: []string{"b", "c"}},
+				{ID: "b", Content: "two"},
+			},
+			wantReport: Report{UnmappedFields: map[string]int{}, Skipped: map[string]int{}},
+		},
+		{
+			name: "deleted and recurring tasks are skipped",
+			input: `[{"uuid":"a","description":"gone","status":"deleted","end":"20250601T080000Z"},
+				{"uuid":"b","description":"template","status":"recurring"},
+				{"uuid":"c","description":"kept","status":"waiting"}]`,
+			want:       []tasklist.Task{{ID: "c", Content: "kept"}},
+			wantReport: Report{UnmappedFields: map[string]int{}, Skipped: map[string]int{"deleted": 1, "recurring": 1}},
+		},
+		{
+			name:  "unmapped fields and priorities are reported",
+			input: `[{"uuid":"a","description":"x","status":"pending","project":"home","priority":"X"}]`,
+			want:  []tasklist.Task{{ID: "a", Content: "x"}},
+			wantReport: Report{
+				UnmappedFields: map[string]int{"project": 1, "priority": 1},
+				Skipped:        map[string]int{},
+			},
+		},
+		{
+			name:    "invalid date",
+			input:   `[{"uuid":"a","description":"x","status":"pending","due":"tomorrow"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			input:   `[{"uuid":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, report, err := Decode(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Decode() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() tasks =\n  %+v\nwant\n  %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(*report, tt.wantReport) {
+				t.Errorf("Decode() report = %+v, want %+v", *report, tt.wantReport)
+			}
+		})
+	}
+}
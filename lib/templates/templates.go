@@ -0,0 +1,304 @@
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/utils"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("template not found")
+
+	namePattern     = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+	variablePattern = regexp.MustCompile(`{{\s*([A-Za-z0-9_.-]+)\s*}}`)
+)
+
+// Template is a named, reusable set of tasks.
+type Template struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Tasks       []Item `json:"tasks"`
+}
+
+// Item is a task in a template. Text fields may reference variables as
+// {{name}}; Due is an offset such as -2d or 1w from the date the template is
+// applied for.
+type Item struct {
+	Content  string            `json:"content"`
+	Priority tasklist.Priority `json:"priority,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Due      string            `json:"due,omitempty"`
+	Comments []string          `json:"comments,omitempty"`
+	Subtasks []Item            `json:"subtasks,omitempty"`
+}
+
+// Dir returns the directory templates are stored in.
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mytodo", "templates"), nil
+}
+
+func path(name string) (string, error) {
+	if !namePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid template name %q: use letters, digits, - and _", name)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// List returns the names of all stored templates, sorted.
+func List() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Load reads the template with the given name.
+func Load(name string) (*Template, error) {
+	p, err := path(name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var t Template
+	if err := json.Unmarshal(content, &t); err != nil {
+		return nil, fmt.Errorf("reading template %s: %w", name, err)
+	}
+	t.Name = name
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	return &t, nil
+}
+
+// Save writes the template, replacing any template with the same name.
+func Save(t *Template) error {
+	if err := t.validate(); err != nil {
+		return err
+	}
+	p, err := path(t.Name)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(p, append(content, '\n'), 0600)
+}
+
+// Remove deletes the template with the given name.
+func Remove(name string) error {
+	p, err := path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *Template) validate() error {
+	if len(t.Tasks) == 0 {
+		return fmt.Errorf("template has no tasks")
+	}
+	var check func(items []Item) error
+	check = func(items []Item) error {
+		for _, item := range items {
+			if strings.TrimSpace(item.Content) == "" {
+				return fmt.Errorf("template task without content")
+			}
+			if item.Due != "" {
+				if _, err := utils.ParseOffset(item.Due, time.Now()); err != nil {
+					return fmt.Errorf("task %q: %w", item.Content, err)
+				}
+			}
+			if err := check(item.Subtasks); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return check(t.Tasks)
+}
+
+// Variables returns the names of the variables the template uses, sorted.
+func (t *Template) Variables() []string {
+	seen := map[string]bool{}
+	var walk func(items []Item)
+	walk = func(items []Item) {
+		for _, item := range items {
+			texts := append([]string{item.Content}, item.Tags...)
+			for _, text := range append(texts, item.Comments...) {
+				for _, match := range variablePattern.FindAllStringSubmatch(text, -1) {
+					seen[match[1]] = true
+				}
+			}
+			walk(item.Subtasks)
+		}
+	}
+	walk(t.Tasks)
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Instantiate turns the template into tasks, parents before their subtasks.
+// Due offsets are applied to anchor. Every variable the template uses must be
+// given in vars.
+func (t *Template) Instantiate(vars map[string]string, anchor time.Time) ([]tasklist.Task, error) {
+	var missing []string
+	for _, name := range t.Variables() {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing value for %s (pass --var name=value)", strings.Join(missing, ", "))
+	}
+
+	expand := func(s string) string {
+		return variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+			return vars[variablePattern.FindStringSubmatch(match)[1]]
+		})
+	}
+
+	var tasks []tasklist.Task
+	var add func(items []Item, parentID string) error
+	add = func(items []Item, parentID string) error {
+		for _, item := range items {
+			task := tasklist.Task{
+				ID:       tasklist.NewTaskID(),
+				Content:  expand(item.Content),
+				Priority: item.Priority,
+				ParentID: parentID,
+			}
+			for _, tag := range item.Tags {
+				task.Tags = append(task.Tags, expand(tag))
+			}
+			for _, comment := range item.Comments {
+				task.Comments = append(task.Comments, tasklist.Comment{Text: expand(comment)})
+			}
+			if item.Due != "" {
+				due, err := utils.ParseOffset(item.Due, anchor)
+				if err != nil {
+					return fmt.Errorf("task %q: %w", item.Content, err)
+				}
+				task.Due = &due
+			}
+			tasks = append(tasks, task)
+			if err := add(item.Subtasks, task.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := add(t.Tasks, ""); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// FromTasks captures tasks as a template. Tasks whose parent is among them
+// become its subtasks. Due dates are stored relative to the latest due date,
+// so applying the template with --due sets that date and shifts the rest.
+func FromTasks(name string, tasks []tasklist.Task) *Template {
+	var anchor *time.Time
+	for _, task := range tasks {
+		if task.Due != nil && (anchor == nil || task.Due.After(*anchor)) {
+			anchor = task.Due
+		}
+	}
+
+	included := map[string]bool{}
+	for _, task := range tasks {
+		if task.ID != "" {
+			included[task.ID] = true
+		}
+	}
+
+	var build func(parentID string) []Item
+	build = func(parentID string) []Item {
+		var items []Item
+		for _, task := range tasks {
+			isRoot := task.ParentID == "" || !included[task.ParentID]
+			if (parentID == "" && !isRoot) || (parentID != "" && task.ParentID != parentID) {
+				continue
+			}
+			item := Item{
+				Content:  task.Content,
+				Priority: task.Priority,
+				Tags:     task.Tags,
+			}
+			for _, comment := range task.Comments {
+				item.Comments = append(item.Comments, comment.Text)
+			}
+			if task.Due != nil {
+				item.Due = fmt.Sprintf("%dd", daysBetween(*anchor, *task.Due))
+			}
+			if task.ID != "" {
+				item.Subtasks = build(task.ID)
+			}
+			items = append(items, item)
+		}
+		return items
+	}
+
+	return &Template{Name: name, Tasks: build("")}
+}
+
+// daysBetween counts calendar days from a to b, ignoring the time of day.
+func daysBetween(a, b time.Time) int {
+	a, b = a.Local(), b.Local()
+	a = time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	b = time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
@@ -0,0 +1,578 @@
+package tui
+
+import (
+	"fmt"
+	"mytodo/lib/tasklist"
+	"mytodo/lib/utils"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Run starts the full-screen interface on the task list and returns when the
+// user quits. Every change is saved through the task list immediately.
+func Run(tasks *tasklist.TaskList) error {
+	_, err := tea.NewProgram(newModel(tasks), tea.WithAltScreen()).Run()
+	return err
+}
+
+type mode int
+
+const (
+	modeBrowse mode = iota
+	modeEdit
+	modeComment
+	modeAdd
+	modeFilter
+	modeConfirmDelete
+)
+
+type view int
+
+const (
+	viewAll view = iota
+	viewOpen
+	viewDone
+)
+
+func (v view) String() string {
+	switch v {
+	case viewOpen:
+		return "open"
+	case viewDone:
+		return "done"
+	default:
+		return "all"
+	}
+}
+
+// The detail pane moves beside the list once the terminal is this wide.
+const sideBySideWidth = 100
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6"))
+	cursorStyle   = lipgloss.NewStyle().Bold(true).Reverse(true)
+	doneStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	pendingStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	snoozedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	overdueStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	helpStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+	detailStyle   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8")).Padding(0, 1)
+	labelStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	selectedStyle = lipgloss.NewStyle().Bold(true)
+)
+
+type model struct {
+	tasks *tasklist.TaskList
+
+	visible []int // indices into tasks.Tasks, in display order
+	cursor  int   // position in visible
+	offset  int   // first visible row on screen
+
+	view       view
+	filter     string
+	showDetail bool
+
+	mode  mode
+	input textinput.Model
+
+	status   string
+	isError  bool
+	quitting bool
+
+	width, height int
+}
+
+func newModel(tasks *tasklist.TaskList) *model {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.CharLimit = 500
+
+	m := &model{tasks: tasks, input: input, showDetail: true, width: 80, height: 24}
+	m.refresh(-1)
+	return m
+}
+
+func (m *model) Init() tea.Cmd {
+	return nil
+}
+
+// refresh rebuilds the visible rows and keeps the cursor on the task with
+// index keep when it is still visible.
+func (m *model) refresh(keep int) {
+	now := time.Now()
+	needle := strings.ToLower(m.filter)
+
+	m.visible = m.visible[:0]
+	for i := range m.tasks.Tasks {
+		task := &m.tasks.Tasks[i]
+		switch m.view {
+		case viewOpen:
+			if task.Done || task.IsSnoozed(now) {
+				continue
+			}
+		case viewDone:
+			if !task.Done {
+				continue
+			}
+		}
+		if needle != "" && !matches(task, needle) {
+			continue
+		}
+		m.visible = append(m.visible, i)
+	}
+
+	if keep >= 0 {
+		for pos, index := range m.visible {
+			if index == keep {
+				m.cursor = pos
+				break
+			}
+		}
+	}
+	m.cursor = max(0, min(m.cursor, len(m.visible)-1))
+}
+
+// matches looks for needle in the content, tags and comments of the task.
+func matches(task *tasklist.Task, needle string) bool {
+	if strings.Contains(strings.ToLower(task.Content), needle) {
+		return true
+	}
+	for _, tag := range task.Tags {
+		if strings.Contains(strings.ToLower(tag), strings.TrimPrefix(needle, "#")) {
+			return true
+		}
+	}
+	for _, comment := range task.Comments {
+		if strings.Contains(strings.ToLower(comment.Text), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// selected returns the index of the task under the cursor, or -1.
+func (m *model) selected() int {
+	if len(m.visible) == 0 {
+		return -1
+	}
+	return m.visible[m.cursor]
+}
+
+func (m *model) setStatus(format string, args ...interface{}) {
+	m.status = fmt.Sprintf(format, args...)
+	m.isError = false
+}
+
+func (m *model) setError(err error) {
+	m.status = "Error: " + err.Error()
+	m.isError = true
+}
+
+// -----------------------------------------------------------------------------
+// Update
+// -----------------------------------------------------------------------------
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.input.Width = max(10, msg.Width-4)
+		return m, nil
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.quitting = true
+			return m, tea.Quit
+		}
+		switch m.mode {
+		case modeBrowse:
+			return m.updateBrowse(msg)
+		case modeConfirmDelete:
+			return m.updateConfirmDelete(msg)
+		default:
+			return m.updateInput(msg)
+		}
+	}
+	return m, nil
+}
+
+func (m *model) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	index := m.selected()
+
+	switch msg.String() {
+	case "q", "esc":
+		if msg.String() == "esc" && m.filter != "" {
+			m.filter = ""
+			m.refresh(index)
+			return m, nil
+		}
+		m.quitting = true
+		return m, tea.Quit
+	case "up", "k":
+		m.cursor = max(0, m.cursor-1)
+	case "down", "j":
+		m.cursor = max(0, min(m.cursor+1, len(m.visible)-1))
+	case "pgup":
+		m.cursor = max(0, m.cursor-m.listHeight())
+	case "pgdown":
+		m.cursor = max(0, min(m.cursor+m.listHeight(), len(m.visible)-1))
+	case "home", "g":
+		m.cursor = 0
+	case "end", "G":
+		m.cursor = max(0, len(m.visible)-1)
+	case " ", "x":
+		m.toggleDone(index)
+	case "enter", "tab":
+		m.showDetail = !m.showDetail
+	case "v":
+		m.view = (m.view + 1) % 3
+		m.refresh(index)
+		m.setStatus("Showing %s tasks", m.view)
+	case "r":
+		if err := m.tasks.Reload(); err != nil {
+			m.setError(err)
+			break
+		}
+		m.refresh(index)
+		m.setStatus("Reloaded %s", m.tasks.FilePath())
+	case "/":
+		return m, m.startInput(modeFilter, "Filter: ", m.filter)
+	case "a":
+		return m, m.startInput(modeAdd, "New task: ", "")
+	case "e":
+		if index >= 0 {
+			return m, m.startInput(modeEdit, "Edit: ", m.tasks.Tasks[index].Content)
+		}
+	case "c":
+		if index >= 0 {
+			return m, m.startInput(modeComment, "Comment: ", "")
+		}
+	case "d", "delete":
+		if index >= 0 {
+			m.mode = modeConfirmDelete
+		}
+	}
+	return m, nil
+}
+
+func (m *model) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.mode = modeBrowse
+	if msg.String() != "y" {
+		m.setStatus("Kept the task")
+		return m, nil
+	}
+
+	index := m.selected()
+	content := m.tasks.Tasks[index].Content
+	if err := m.tasks.RemoveTask(index); err != nil {
+		m.setError(err)
+		return m, nil
+	}
+	m.refresh(-1)
+	m.setStatus("Removed %q", content)
+	return m, nil
+}
+
+func (m *model) startInput(next mode, prompt, value string) tea.Cmd {
+	m.mode = next
+	m.input.Prompt = prompt
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+	return m.input.Focus()
+}
+
+func (m *model) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		if m.mode == modeFilter {
+			m.filter = ""
+			m.refresh(m.selected())
+		}
+		m.mode = modeBrowse
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		value := strings.TrimSpace(m.input.Value())
+		current := m.mode
+		m.mode = modeBrowse
+		m.input.Blur()
+		m.submit(current, value)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	if m.mode == modeFilter {
+		// Filter as you type.
+		m.filter = m.input.Value()
+		m.refresh(m.selected())
+	}
+	return m, cmd
+}
+
+func (m *model) submit(current mode, value string) {
+	index := m.selected()
+
+	switch current {
+	case modeFilter:
+		m.filter = value
+		m.refresh(index)
+	case modeAdd:
+		if value == "" {
+			return
+		}
+		if err := m.tasks.AddTask(&tasklist.Task{Content: value}); err != nil {
+			m.setError(err)
+			return
+		}
+		m.refresh(len(m.tasks.Tasks) - 1)
+		m.setStatus("Added %q", value)
+	case modeEdit:
+		if value == "" || index < 0 {
+			return
+		}
+		task := m.tasks.GetTask(index)
+		task.Content = value
+		if err := m.tasks.ReplaceTask(index, task); err != nil {
+			m.setError(err)
+			return
+		}
+		m.refresh(index)
+		m.setStatus("Saved")
+	case modeComment:
+		if value == "" || index < 0 {
+			return
+		}
+		if err := m.tasks.AddComment(index, value); err != nil {
+			m.setError(err)
+			return
+		}
+		m.showDetail = true
+		m.setStatus("Comment added")
+	}
+}
+
+func (m *model) toggleDone(index int) {
+	if index < 0 {
+		return
+	}
+	task := m.tasks.GetTask(index)
+	task.Done = !task.Done
+	if err := m.tasks.ReplaceTask(index, task); err != nil {
+		m.setError(err)
+		return
+	}
+	m.refresh(index)
+	if task.Done {
+		m.setStatus("Marked %q as done", task.Content)
+	} else {
+		m.setStatus("Marked %q as not done", task.Content)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// View
+// -----------------------------------------------------------------------------
+
+func (m *model) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	header := titleStyle.Render("mytodo") + helpStyle.Render(fmt.Sprintf("  %d of %d tasks · view: %s", len(m.visible), len(m.tasks.Tasks), m.view))
+	if m.filter != "" {
+		header += helpStyle.Render(" · filter: ") + m.filter
+	}
+
+	sideBySide := m.showDetail && m.width >= sideBySideWidth
+	listWidth := m.width
+	if sideBySide {
+		listWidth = m.width / 2
+	}
+
+	list := m.renderList(listWidth)
+	body := list
+	if m.showDetail {
+		if sideBySide {
+			list = lipgloss.NewStyle().Width(listWidth).Render(list)
+			body = lipgloss.JoinHorizontal(lipgloss.Top, list, m.renderDetail(m.width-listWidth-1))
+		} else {
+			body = lipgloss.JoinVertical(lipgloss.Left, list, m.renderDetail(m.width-2))
+		}
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, "", body, m.renderFooter())
+}
+
+// listHeight is the number of task rows that fit on screen.
+func (m *model) listHeight() int {
+	reserved := 4 // header, blank line, status, help
+	if m.showDetail && m.width < sideBySideWidth {
+		reserved += m.detailHeight()
+	}
+	return max(1, m.height-reserved)
+}
+
+func (m *model) detailHeight() int {
+	return max(6, m.height/3)
+}
+
+func (m *model) renderList(width int) string {
+	if len(m.visible) == 0 {
+		if len(m.tasks.Tasks) == 0 {
+			return helpStyle.Render("No tasks yet. Press a to add one.")
+		}
+		return helpStyle.Render("No tasks match. Press esc to clear the filter or v to change the view.")
+	}
+
+	height := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.visible)-height))
+
+	now := time.Now()
+	var rows []string
+	for pos := m.offset; pos < len(m.visible) && pos < m.offset+height; pos++ {
+		index := m.visible[pos]
+		row := m.renderRow(index, &m.tasks.Tasks[index], now, width)
+		if pos == m.cursor {
+			row = cursorStyle.Render(row)
+		}
+		rows = append(rows, row)
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (m *model) renderRow(index int, task *tasklist.Task, now time.Time, width int) string {
+	icon, style := "⏳", pendingStyle
+	switch {
+	case task.Done:
+		icon, style = "✔ ", doneStyle
+	case task.IsSnoozed(now):
+		icon, style = "💤", snoozedStyle
+	}
+
+	line := fmt.Sprintf("%s %3d. %s", icon, index, task.Content)
+	if task.ParentID != "" {
+		line = "  ↳ " + line
+	}
+	if task.Priority != tasklist.PriorityNone {
+		line += fmt.Sprintf(" (%s)", task.Priority)
+	}
+	for _, tag := range task.Tags {
+		line += " #" + tag
+	}
+	line = truncate(line, width-1)
+
+	if task.Due != nil && !task.Done && task.Due.Before(now) {
+		style = overdueStyle
+	}
+	return style.Render(line)
+}
+
+func (m *model) renderDetail(width int) string {
+	width = max(20, width)
+	index := m.selected()
+	if index < 0 {
+		return detailStyle.Width(width - 2).Render(helpStyle.Render("No task selected"))
+	}
+	task := &m.tasks.Tasks[index]
+
+	var sb strings.Builder
+	sb.WriteString(selectedStyle.Render(task.Content) + "\n\n")
+
+	field := func(label, value string) {
+		sb.WriteString(labelStyle.Render(fmt.Sprintf("%-10s", label)) + value + "\n")
+	}
+	status := "Pending"
+	if task.Done {
+		status = "Done"
+		if task.CompletedAt != nil {
+			status += " " + utils.FormatDate(*task.CompletedAt)
+		}
+	}
+	field("Status", status)
+	if task.Due != nil {
+		field("Due", utils.FormatDate(*task.Due))
+	}
+	if task.Priority != tasklist.PriorityNone {
+		field("Priority", string(task.Priority))
+	}
+	if len(task.Tags) > 0 {
+		field("Tags", strings.Join(task.Tags, ", "))
+	}
+	if task.Recurrence != "" {
+		field("Repeats", task.Recurrence)
+	}
+	if task.Snoozed != nil {
+		field("Snoozed", "until "+utils.FormatDate(*task.Snoozed))
+	}
+	if task.CreatedAt != nil {
+		field("Created", utils.FormatDate(*task.CreatedAt))
+	}
+
+	if len(task.Links) > 0 {
+		sb.WriteString("\n" + labelStyle.Render("Links") + "\n")
+		for _, link := range task.Links {
+			sb.WriteString("🔗 " + link.String() + "\n")
+		}
+	}
+
+	sb.WriteString("\n" + labelStyle.Render("Comments") + "\n")
+	if len(task.Comments) == 0 {
+		sb.WriteString(helpStyle.Render("none, press c to add one"))
+	}
+	for _, comment := range task.Comments {
+		line := "- " + comment.Text
+		if comment.At != nil {
+			line += labelStyle.Render("  " + utils.FormatDate(*comment.At))
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	style := detailStyle.Width(width - 2)
+	if m.width < sideBySideWidth {
+		style = style.MaxHeight(m.detailHeight())
+	}
+	return style.Render(strings.TrimRight(sb.String(), "\n"))
+}
+
+func (m *model) renderFooter() string {
+	var status string
+	switch m.mode {
+	case modeBrowse:
+		status = m.status
+		if m.isError {
+			status = errorStyle.Render(status)
+		}
+	case modeConfirmDelete:
+		status = errorStyle.Render(fmt.Sprintf("Delete %q? (y/N)", m.tasks.Tasks[m.selected()].Content))
+	default:
+		status = m.input.View()
+	}
+
+	help := "↑/↓ move · space done · e edit · c comment · a add · d delete · / filter · v view · enter details · r reload · q quit"
+	if m.mode != modeBrowse && m.mode != modeConfirmDelete {
+		help = "enter save · esc cancel"
+	}
+	return status + "\n" + helpStyle.Render(truncate(help, m.width))
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 || lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
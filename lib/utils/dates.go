@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseWhen turns a human date into a point in time relative to now.
+// Accepted forms:
+//   - today, tomorrow
+//   - weekday names (monday, mon, ...) meaning the next such day after today
+//   - relative offsets: 3d, 2w, 1m (optionally prefixed with +)
+//   - YYYY-MM-DD, YYYY-MM-DD HH:MM and RFC 3339
+//
+// Date-only forms resolve to midnight local time.
+func ParseWhen(value string, now time.Time) (time.Time, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch s {
+	case "":
+		return time.Time{}, fmt.Errorf("empty date")
+	case "now":
+		return now, nil
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	if weekday, ok := parseWeekday(s); ok {
+		days := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+
+	if t, ok := parseOffset(s, today); ok {
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(value)); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q (try tomorrow, friday, 3d, 2w or YYYY-MM-DD)", value)
+}
+
+// ParseOffset applies an offset such as 3d, -1w or +2m to base.
+func ParseOffset(value string, base time.Time) (time.Time, error) {
+	if t, ok := parseOffset(strings.ToLower(strings.TrimSpace(value)), base); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid offset %q (use e.g. 3d, -1w, 2m)", value)
+}
+
+func parseOffset(s string, base time.Time) (time.Time, bool) {
+	if len(s) < 2 {
+		return time.Time{}, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(s[:len(s)-1], "+"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	switch s[len(s)-1] {
+	case 'd':
+		return base.AddDate(0, 0, n), true
+	case 'w':
+		return base.AddDate(0, 0, 7*n), true
+	case 'm':
+		return base.AddDate(0, n, 0), true
+	case 'y':
+		return base.AddDate(n, 0, 0), true
+	}
+	return time.Time{}, false
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// FormatDate renders a date without the time part when it is midnight.
+func FormatDate(t time.Time) string {
+	t = t.Local()
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -1,10 +1,11 @@
 package utils
 
 import (
-	"os"
-	"strings"
+	"mytodo/lib/config"
+	"mytodo/lib/credentials"
 )
 
+// Environment variables that override the config files; see lib/config.
 const (
 	AIEnabledEnvVar   = "USE_AI"
 	OpenAITokenEnvVar = "OPEN_AI_API_KEY"
@@ -13,43 +14,63 @@ const (
 	JiraTokenEnvVar   = "JIRA_TOKEN"
 	JiraProjectKeyEnv = "JIRA_PROJECT_KEY"
 	QuipTokenEnvVar   = "QUIP_TOKEN"
+	TaskFileEnvVar    = "MYTODO_FILE"
+	APITokenEnvVar    = "MYTODO_API_TOKEN"
 )
 
+// GetTaskFile returns the configured task file location, or an empty string
+// when the default should be used.
+func GetTaskFile() string {
+	return config.Get("storage.file")
+}
+
 func GetJiraURL() string {
-	return os.Getenv(JiraURLEnvVar)
+	return config.Get("jira.url")
 }
 
 func GetJiraEmail() string {
-	return os.Getenv(JiraEmailEnvVar)
+	return config.Get("jira.email")
 }
 
-func GetJiraToken() string {
-	return os.Getenv(JiraTokenEnvVar)
+// GetJiraToken returns the JIRA API token of the active profile; see secret.
+func GetJiraToken() (string, error) {
+	return secret(credentials.Jira, "jira.token")
 }
 
 func GetProjectKey() string {
-	return os.Getenv(JiraProjectKeyEnv)
+	return config.Get("jira.project")
 }
 
-func TrimResponse(s string) string {
-	begin := strings.Index(s, "[")
-	end := strings.LastIndex(s, "]")
-
-	if begin != -1 && end != -1 {
-		return s[begin : end+1]
-	}
+// AgentEnabled reports whether AI features are on. Configuring an OpenAI key
+// turns them on; a key kept only in the credentials file needs agent.enabled,
+// as finding it means unlocking the file.
+func AgentEnabled() bool {
+	return config.Bool("agent.enabled") || config.Get("agent.api_key") != "" || config.Get("agent.api_key_command") != ""
+}
 
-	return s
+// GetOpenAIToken returns the OpenAI API key; see secret.
+func GetOpenAIToken() (string, error) {
+	return secret(credentials.OpenAI, "agent.api_key")
 }
 
-func AgentEnabled() bool {
-	return os.Getenv(AIEnabledEnvVar) != "" || os.Getenv(OpenAITokenEnvVar) != ""
+// GetQuipToken returns the Quip access token of the active profile; see
+// secret.
+func GetQuipToken() (string, error) {
+	return secret(credentials.Quip, "quip.token")
 }
 
-func GetOpenAIToken() string {
-	return os.Getenv(OpenAITokenEnvVar)
+// secret resolves a secret setting: its value from the config files or the
+// environment, else the output of its <name>_command helper, else the entry
+// stored with "mytodo auth login".
+func secret(service, name string) (string, error) {
+	profile := ""
+	if key, err := config.FindKey(name); err == nil && key.Profile {
+		profile = config.ActiveProfile()
+	}
+	value, _, err := credentials.Resolve(service, profile, config.Get(name), config.Get(name+"_command"))
+	return value, err
 }
 
-func GetQuipToken() string {
-	return os.Getenv(QuipTokenEnvVar)
+func GetAPIToken() string {
+	return config.Get("server.api_token")
 }
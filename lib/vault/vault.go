@@ -0,0 +1,288 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	envelopeFormat = "mytodo-encrypted-v1"
+	kdfName        = "pbkdf2-sha256"
+
+	// OWASP's 2023 recommendation for PBKDF2-HMAC-SHA256.
+	DefaultIterations = 600000
+
+	keyLength  = 32 // AES-256
+	saltLength = 16
+
+	PassphraseEnvVar = "MYTODO_PASSPHRASE"
+	KeyFileEnvVar    = "MYTODO_KEY_FILE"
+	SessionEnvVar    = "MYTODO_SESSION_KEY"
+)
+
+var ErrWrongKey = errors.New("wrong passphrase or key file")
+
+// Interactive allows prompting for the passphrase on the terminal. Callers
+// that must never block on input, such as shell completion, turn it off.
+var Interactive = true
+
+// envelope is the on-disk format of an encrypted task file. []byte fields are
+// base64 encoded by encoding/json.
+type envelope struct {
+	Format     string `json:"format"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Vault encrypts task file content with AES-256-GCM under a key derived from
+// a passphrase or key file. It implements tasklist.Codec.
+type Vault struct {
+	key        []byte
+	salt       []byte
+	iterations int
+}
+
+// IsEncrypted reports whether content is an encrypted task file.
+func IsEncrypted(content []byte) bool {
+	var env envelope
+	if err := json.Unmarshal(content, &env); err != nil {
+		return false
+	}
+	return env.Format == envelopeFormat
+}
+
+// New derives a key from secret under a fresh salt, for encrypting a file
+// that is not encrypted yet.
+func New(secret []byte) (*Vault, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(secret, salt, DefaultIterations)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{key: key, salt: salt, iterations: DefaultIterations}, nil
+}
+
+// Open unlocks the encrypted content, resolving the key through the session
+// key, key file, passphrase environment variable or a terminal prompt.
+func Open(content []byte) (*Vault, error) {
+	return OpenFor("task file", content)
+}
+
+// OpenFor is Open for other encrypted files; what names the file in prompts
+// and errors.
+func OpenFor(what string, content []byte) (*Vault, error) {
+	var env envelope
+	if err := json.Unmarshal(content, &env); err != nil || env.Format != envelopeFormat {
+		return nil, fmt.Errorf("not an encrypted task file")
+	}
+	if env.KDF != kdfName {
+		return nil, fmt.Errorf("unsupported key derivation %q", env.KDF)
+	}
+
+	v := &Vault{salt: env.Salt, iterations: env.Iterations}
+
+	if key, ok := sessionKey(env.Salt); ok {
+		v.key = key
+	} else {
+		secret, err := ResolveSecretFor(what, false)
+		if err != nil {
+			return nil, err
+		}
+		if v.key, err = deriveKey(secret, env.Salt, env.Iterations); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fail early on a wrong key rather than on first use.
+	if _, err := v.Decode(content); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Encode seals plain with a fresh nonce.
+func (v *Vault) Encode(plain []byte) ([]byte, error) {
+	gcm, err := v.aead()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	env := envelope{
+		Format:     envelopeFormat,
+		KDF:        kdfName,
+		Iterations: v.iterations,
+		Salt:       v.salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plain, []byte(envelopeFormat)),
+	}
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// Decode opens stored, which must be an envelope sealed under this key.
+func (v *Vault) Decode(stored []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(stored, &env); err != nil || env.Format != envelopeFormat {
+		return nil, fmt.Errorf("not an encrypted task file")
+	}
+	if !bytes.Equal(env.Salt, v.salt) {
+		return nil, fmt.Errorf("task file was encrypted with a different salt; unlock it separately")
+	}
+
+	gcm, err := v.aead()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, env.Nonce, env.Ciphertext, []byte(envelopeFormat))
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plain, nil
+}
+
+// SessionKey returns a value for MYTODO_SESSION_KEY that unlocks this file
+// without deriving the key again.
+func (v *Vault) SessionKey() string {
+	return base64.RawURLEncoding.EncodeToString(v.salt) + "." + hex.EncodeToString(v.key)
+}
+
+func (v *Vault) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(v.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ResolveSecret returns the key file content or passphrase to derive the key
+// from. With confirm set, an interactive prompt asks twice.
+func ResolveSecret(confirm bool) ([]byte, error) {
+	return ResolveSecretFor("task file", confirm)
+}
+
+// ResolveSecretFor is ResolveSecret for other encrypted files; what names the
+// file in prompts and errors.
+func ResolveSecretFor(what string, confirm bool) ([]byte, error) {
+	if path := os.Getenv(KeyFileEnvVar); path != "" {
+		return ReadKeyFile(path)
+	}
+	if passphrase := os.Getenv(PassphraseEnvVar); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	passphrase, err := promptPassphrase(what, strings.ToUpper(what[:1])+what[1:]+" passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		again, err := promptPassphrase(what, "Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+	return passphrase, nil
+}
+
+// ReadKeyFile reads a key file, ignoring surrounding whitespace.
+func ReadKeyFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", path)
+	}
+	return content, nil
+}
+
+// GenerateKeyFile writes 32 random bytes, hex encoded, to a new file.
+func GenerateKeyFile(path string) error {
+	key := make([]byte, keyLength)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("creating key file: %w", err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(hex.EncodeToString(key) + "\n")
+	return err
+}
+
+func deriveKey(secret, salt []byte, iterations int) ([]byte, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("invalid KDF iteration count %d", iterations)
+	}
+	return pbkdf2.Key(sha256.New, string(secret), salt, iterations, keyLength)
+}
+
+// sessionKey returns the cached key from MYTODO_SESSION_KEY if it belongs to
+// a file with the given salt.
+func sessionKey(salt []byte) ([]byte, bool) {
+	value := os.Getenv(SessionEnvVar)
+	encodedSalt, encodedKey, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false
+	}
+	sessionSalt, err := base64.RawURLEncoding.DecodeString(encodedSalt)
+	if err != nil || !bytes.Equal(sessionSalt, salt) {
+		return nil, false
+	}
+	key, err := hex.DecodeString(encodedKey)
+	if err != nil || len(key) != keyLength {
+		return nil, false
+	}
+	return key, true
+}
+
+// promptPassphrase reads from the controlling terminal rather than stdin, so
+// commands that use stdin for data (import, mcp) can still prompt.
+func promptPassphrase(what, prompt string) ([]byte, error) {
+	if !Interactive {
+		return nil, fmt.Errorf("%s is encrypted: set %s, %s or %s", what, SessionEnvVar, KeyFileEnvVar, PassphraseEnvVar)
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s is encrypted and no terminal is available: set %s, %s or %s", what, SessionEnvVar, KeyFileEnvVar, PassphraseEnvVar)
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return nil, fmt.Errorf("reading passphrase: %w", err)
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,137 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// sealed returns plain encrypted under a vault for secret.
+func sealed(t *testing.T, secret string, plain []byte) (*Vault, []byte) {
+	t.Helper()
+	v, err := New([]byte(secret))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	stored, err := v.Encode(plain)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	return v, stored
+}
+
+func TestEncodeDecode(t *testing.T) {
+	plain := []byte(`{"tasks":[{"content":"secret plans","done":false}]}`)
+	v, stored := sealed(t, "correct horse", plain)
+
+	if bytes.Contains(stored, []byte("secret plans")) {
+		t.Fatal("encoded content contains the plain text")
+	}
+	if !IsEncrypted(stored) {
+		t.Fatal("IsEncrypted() = false for encoded content")
+	}
+	again, err := v.Encode(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(stored, again) {
+		t.Error("two encodings are identical; nonces are not fresh")
+	}
+
+	got, err := v.Decode(stored)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("Decode() = %q, want %q", got, plain)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(stored, &env); err != nil {
+		t.Fatal(err)
+	}
+	env.Ciphertext[0] ^= 0xff
+	tampered, _ := json.Marshal(env)
+	if _, err := v.Decode(tampered); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Decode(tampered) error = %v, want ErrWrongKey", err)
+	}
+
+	other, _ := sealed(t, "correct horse", plain)
+	if _, err := other.Decode(stored); err == nil {
+		t.Error("Decode() with another salt succeeded")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	Interactive = false
+	plain := []byte(`{"tasks":[]}`)
+	v, stored := sealed(t, "correct horse", plain)
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := GenerateKeyFile(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	keySecret, err := ReadKeyFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, keyStored := sealed(t, string(keySecret), plain)
+
+	tests := []struct {
+		name    string
+		stored  []byte
+		env     map[string]string
+		wantErr bool
+		wantIs  error
+	}{
+		{name: "passphrase", stored: stored, env: map[string]string{PassphraseEnvVar: "correct horse"}},
+		{name: "wrong passphrase", stored: stored, env: map[string]string{PassphraseEnvVar: "battery staple"}, wantErr: true, wantIs: ErrWrongKey},
+		{name: "session key", stored: stored, env: map[string]string{SessionEnvVar: v.SessionKey()}},
+		{name: "session key of another file falls back", stored: keyStored, env: map[string]string{SessionEnvVar: v.SessionKey(), KeyFileEnvVar: keyFile}},
+		{name: "key file beats passphrase", stored: keyStored, env: map[string]string{KeyFileEnvVar: keyFile, PassphraseEnvVar: "correct horse"}},
+		{name: "no secret and no terminal", stored: stored, wantErr: true},
+		{name: "plain content", stored: plain, env: map[string]string{PassphraseEnvVar: "correct horse"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{PassphraseEnvVar, KeyFileEnvVar, SessionEnvVar} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			opened, err := Open(tt.stored)
+			if tt.wantErr {
+				if err == nil || (tt.wantIs != nil && !errors.Is(err, tt.wantIs)) {
+					t.Fatalf("Open() error = %v, want %v", err, tt.wantIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			got, err := opened.Decode(tt.stored)
+			if err != nil || !bytes.Equal(got, plain) {
+				t.Errorf("Decode() = %q, %v; want %q", got, err, plain)
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{`{"tasks":[]}`, false},
+		{`not json`, false},
+		{`{"format":"mytodo-tasks","version":2,"tasks":[]}`, false},
+		{`{"format":"mytodo-encrypted-v1"}`, true},
+	}
+	for _, tt := range tests {
+		if got := IsEncrypted([]byte(tt.content)); got != tt.want {
+			t.Errorf("IsEncrypted(%s) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}